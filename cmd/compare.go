@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/pkg"
+)
+
+// runCompare compares the submissions of a cohort with each other, prints the report,
+// exports the similarity graph to graphFile if set and returns the exit code
+func runCompare(ctx context.Context, cohort, graphFile string, opts pkg.CompareOptions) int {
+	fmt.Fprintf(os.Stderr, "Fingerprinting submissions...\n")
+	progress := &progressWriter{}
+	opts.WFP.Progress = progress
+	report, err := pkg.CompareCohort(ctx, cohort, opts)
+	if progress.bar != nil {
+		progress.bar.Finish()
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error comparing cohort: %v\n", err)
+		return exitScanError
+	}
+
+	if graphFile != "" {
+		if err := writeGraph(graphFile, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing graph: %v\n", err)
+			return exitScanError
+		}
+		fmt.Fprintf(os.Stderr, "Similarity graph written to: %s\n", graphFile)
+	}
+
+	jsonOutput, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating JSON: %v\n", err)
+		return exitScanError
+	}
+	fmt.Println(string(jsonOutput))
+	return exitPass
+}
+
+// writeGraph exports the similarity graph of a report, in DOT format for .dot and .gv files
+// and in JSON otherwise
+func writeGraph(fileName string, report *models.CohortReport) error {
+	out, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".dot", ".gv":
+		err = pkg.WriteGraphDOT(out, report)
+	default:
+		err = pkg.WriteGraphJSON(out, report)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
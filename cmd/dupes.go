@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/pkg"
+)
+
+// runDupes reports the code duplicated within a tree in the given format (json or text)
+// and returns the exit code
+func runDupes(ctx context.Context, root, format string, opts pkg.DupesOptions) int {
+	fmt.Fprintf(os.Stderr, "Fingerprinting %s...\n", root)
+	report, err := pkg.FindDupes(ctx, root, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error finding duplicates: %v\n", err)
+		return exitScanError
+	}
+
+	if format == "text" {
+		if err := pkg.WriteDupesText(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitScanError
+		}
+		return exitPass
+	}
+	jsonOutput, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating JSON: %v\n", err)
+		return exitScanError
+	}
+	fmt.Println(string(jsonOutput))
+	return exitPass
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/pkg"
+)
+
+// parseInterspersed parses the command line flags found anywhere among the arguments
+// (index build <dirs...> -o corpus.idx) and returns the other arguments
+func parseInterspersed(args []string) []string {
+	var positional []string
+	for {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		if args[0] == "--" {
+			return append(positional, args[1:]...)
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runIndex builds or extends a local corpus index and returns the exit code:
+// index build <inputs...> -o corpus.idx, index add corpus.idx <inputs...>
+func runIndex(ctx context.Context, args []string, outputFile string, opts pkg.WFPOptions, modeSet bool) int {
+	usage := func() int {
+		fmt.Fprintf(os.Stderr, "Usage: %s index build <directory|file.wfp>... -o corpus.idx [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s index add corpus.idx <directory|file.wfp>... [options]\n", os.Args[0])
+		return exitUsage
+	}
+	if len(args) < 2 {
+		return usage()
+	}
+
+	var idx *pkg.CorpusIndex
+	var indexFile string
+	var inputs []string
+	switch args[0] {
+	case "build":
+		if outputFile == "" {
+			return usage()
+		}
+		indexFile, inputs = outputFile, args[1:]
+		idx = pkg.NewIndex(opts.Mode)
+	case "add":
+		if len(args) < 3 {
+			return usage()
+		}
+		indexFile, inputs = args[1], args[2:]
+		var err error
+		if idx, err = pkg.LoadIndex(indexFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading index: %v\n", err)
+			return exitScanError
+		}
+		// Added code is fingerprinted in the mode of the index
+		if !modeSet {
+			opts.Mode = idx.Mode
+		}
+	default:
+		return usage()
+	}
+
+	opts.Progress = nil
+	if err := idx.Add(ctx, inputs, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error indexing: %v\n", err)
+		return exitScanError
+	}
+	if err := idx.Save(indexFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitScanError
+	}
+	fmt.Fprintf(os.Stderr, "Index %s holds %d files\n", indexFile, len(idx.Files))
+	return exitPass
+}
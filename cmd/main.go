@@ -6,17 +6,33 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
 	"github.com/Software-Transparency-Foundation/stf-plagicheck/pkg"
 	"github.com/schollz/progressbar/v3"
 )
 
+// Exit codes
+const (
+	exitPass      = 0 // Scan completed, policy passed (or no policy)
+	exitScanError = 1 // Scan could not be completed
+	exitUsage     = 2 // Invalid command line
+	exitWarn      = 3 // Policy warnings
+	exitFail      = 4 // Policy failures
+)
+
 var (
 	kbName  string = "osskb-core"
 	version string = "dev"
@@ -47,68 +63,393 @@ func (pw *progressWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// stringList collects the values of a flag given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// File extensions of the languages accepted by --lang
+var languageExtensions = map[string]string{
+	"c": ".c", "cpp": ".cpp", "c++": ".cpp", "csharp": ".cs", "cs": ".cs", "go": ".go", "golang": ".go",
+	"java": ".java", "javascript": ".js", "js": ".js", "typescript": ".ts", "ts": ".ts", "kotlin": ".kt",
+	"php": ".php", "python": ".py", "py": ".py", "ruby": ".rb", "rust": ".rs", "scala": ".scala",
+	"shell": ".sh", "bash": ".sh", "swift": ".swift",
+}
+
+// snippetName returns the virtual file name of code read from stdin
+func snippetName(lang string) string {
+	if lang == "" {
+		return "stdin"
+	}
+	ext, ok := languageExtensions[strings.ToLower(lang)]
+	if !ok {
+		ext = "." + strings.TrimPrefix(lang, ".")
+	}
+	return "snippet" + ext
+}
+
+// readStdinWFP reads source code or a WFP stream from stdin and returns it as a WFP
+func readStdinWFP(lang string, opts pkg.WFPOptions) (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading stdin: %v", err)
+	}
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("file=")) {
+		return string(data), nil
+	}
+	return pkg.GenerateWFPFromData(snippetName(lang), data, opts)
+}
+
+// generateWFP streams the WFP of a file or directory to w through a buffer
+func generateWFP(ctx context.Context, path string, w io.Writer, opts pkg.WFPOptions) error {
+	buffered := bufio.NewWriter(w)
+	if err := pkg.GenerateWFP(ctx, path, buffered, opts); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
 func main() {
 	generateMode := flag.Bool("fp", false, "Generate WFP from file or directory (output only, no scan)")
 	outputFile := flag.String("output", "", "Output file for generated WFP (optional, default: stdout)")
+	flag.StringVar(outputFile, "o", "", "Shorthand for --output (the index file of index build)")
 	minHits := flag.Int("min-hits", 3, "Minimum number of hits required for valid snippet match (default: 3)")
 	numThreads := flag.Int("T", 3, "Number of parallel threads for processing files (default: 3)")
+	hpsm := flag.Bool("hpsm", false, "Include High Precision Snippet Matching (hpsm=) line hashes in the generated WFP")
+	noIgnore := flag.Bool("no-ignore", false, "Do not honour .gitignore and .plagicheckignore files when walking directories")
+	var includes, excludes, skipExts stringList
+	flag.Var(&includes, "include", "Only fingerprint files matching the glob (repeatable)")
+	flag.Var(&excludes, "exclude", "Skip files and directories matching the glob (repeatable)")
+	flag.Var(&skipExts, "skip-ext", "Comma-separated file extensions to skip in addition to the built-in list (repeatable)")
+	minFileSize := flag.Int64("min-file-size", 0, "Skip files of this size in bytes or smaller, 0 keeps every file (default: 100)")
+	maxFileSize := flag.Int64("max-file-size", 0, "Skip files larger than this size in bytes (default: no limit)")
+	pathPrefix := flag.String("path-prefix", "", "Logical prefix prepended to the file paths recorded in the WFP")
+	absolutePaths := flag.Bool("absolute-paths", false, "Record absolute file paths in the WFP instead of paths relative to the scan root")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Follow symbolic links when walking directories")
+	archives := flag.Bool("archives", false, "Fingerprint the members of zip, jar and tar archives instead of skipping them")
+	archiveDepth := flag.Int("archive-depth", pkg.DefaultArchiveDepth, "Maximum nesting of archives within archives")
+	archiveMaxSize := flag.Int64("archive-max-size", pkg.DefaultArchiveMaxSize, "Maximum uncompressed bytes read from each archive")
+	gitDiff := flag.String("git-diff", "", "Only fingerprint the files changed in a <base>..<head> revision range of the local repository")
+	changedLinesOnly := flag.Bool("changed-lines-only", false, "With --git-diff, only match the changed lines of each file")
+	includeGenerated := flag.Bool("include-generated", false, "Fingerprint files marked as generated code (\"Code generated ... DO NOT EDIT\", @generated)")
+	mode := flag.String("mode", "standard", "WFP normalization mode: standard, strip-comments, strip-literals or tokens (all but standard require -fp, compare, dupes, index or --index)")
+	lang := flag.String("lang", "", "Language of the code read from stdin (go, python, js, ... or a file extension)")
+	fpThreads := flag.Int("fp-threads", 0, "Number of parallel threads for WFP generation (default: same as -T)")
 	debugMode := flag.Bool("d", false, "Enable debug mode (show detailed processing information)")
 	showVersion := flag.Bool("version", false, "Show version information")
-	flag.Parse()
+	settingsFile := flag.String("settings", "", "Project settings file with identified components and file filters (default: "+pkg.SettingsFileName+" if present)")
+	showIdentified := flag.Bool("show-identified", false, "Include matches of identified components in the output")
+	licenseMapFile := flag.String("license-map", "", "Local license mapping file used instead of the KB license tables")
+	policyFile := flag.String("policy", "", "Policy file evaluated after the scan (sets the exit code)")
+	templateDir := flag.String("template", "", "Starter code (file or directory) whose hashes are removed from the scanned files before matching")
+	cohort := flag.String("cohort", "", "With compare, directory holding one submission per subdirectory")
+	clusterThreshold := flag.Float64("cluster-threshold", pkg.DefaultClusterThreshold, "With compare, similarity percentage linking two submissions into a cluster")
+	graphFile := flag.String("graph", "", "With compare, export the similarity graph to a file (GraphViz DOT for .dot/.gv, JSON otherwise)")
+	maxSubmissions := flag.Int("max-submissions", pkg.DefaultMaxSubmissions, "With compare, ignore hashes found in more submissions than this (common code)")
+	indexFile := flag.String("index", "", "Scan against a local corpus index (see index build) instead of the KB")
+	minLines := flag.Int("min-lines", pkg.DefaultMinDupeLines, "With dupes, minimum number of lines every copy of a duplicated block must span")
+	format := flag.String("format", "json", "With dupes, report format: json or text")
+	// "snippet" reads the code to check from stdin: plagicheck snippet [options]
+	if len(os.Args) > 1 && os.Args[1] == "snippet" {
+		os.Args = append(append([]string{os.Args[0]}, os.Args[2:]...), "-")
+	}
+	// "compare" matches local code with each other instead of the KB:
+	// plagicheck compare --cohort <dir>, plagicheck compare <target> <reference>
+	compareMode := len(os.Args) > 1 && os.Args[1] == "compare"
+	// "dupes" reports the code duplicated within a tree: plagicheck dupes <dir>
+	dupesMode := len(os.Args) > 1 && os.Args[1] == "dupes"
+	// "index" builds or extends a local corpus index: plagicheck index build|add ...
+	indexMode := len(os.Args) > 1 && os.Args[1] == "index"
+	// "scan" is the default command: plagicheck scan [--index corpus.idx] <path>
+	if compareMode || dupesMode || indexMode || (len(os.Args) > 1 && os.Args[1] == "scan") {
+		os.Args = append([]string{os.Args[0]}, os.Args[2:]...)
+	}
+	// Subcommands taking several arguments accept flags after them
+	var subArgs []string
+	if compareMode || dupesMode || indexMode {
+		subArgs = parseInterspersed(os.Args[1:])
+	} else {
+		flag.Parse()
+	}
 
 	// Set debug mode
 	pkg.SetDebugMode(*debugMode)
 
+	// Stop WFP generation on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	if *showVersion {
 		fmt.Printf("plagicheck version %s (commit: %s)\n", version, commit)
-		os.Exit(0)
+		os.Exit(exitPass)
 	}
 
-	if flag.NArg() != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-fp] [--output <file>] [--min-hits <N>] [-T <threads>] [-d] <file|directory|file.wfp>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "       %s --version\n", os.Args[0])
-		os.Exit(1)
+	if *changedLinesOnly && *gitDiff == "" {
+		fmt.Fprintf(os.Stderr, "Error: --changed-lines-only requires --git-diff\n")
+		os.Exit(exitUsage)
 	}
 
-	path := flag.Arg(0)
-
-	// Determine input type
-	fileInfo, err := os.Stat(path)
+	wfpMode, err := pkg.ParseMode(*mode)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+	modeSet := false
+	flag.Visit(func(f *flag.Flag) { modeSet = modeSet || f.Name == "mode" })
+	if wfpMode != pkg.ModeStandard && !*generateMode && !compareMode && !dupesMode && !indexMode && *indexFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: --mode %s fingerprints cannot be scanned against the knowledge base, use it with -fp, compare, dupes, index or --index\n", wfpMode)
+		os.Exit(exitUsage)
 	}
 
-	isWFPFile := !fileInfo.IsDir() && strings.HasSuffix(strings.ToLower(path), ".wfp")
+	if compareMode && (*cohort == "") == (len(subArgs) != 2) {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare --cohort <directory> [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare <target> <reference> [options]\n", os.Args[0])
+		os.Exit(exitUsage)
+	}
+	if dupesMode && (len(subArgs) != 1 || (*format != "json" && *format != "text")) {
+		fmt.Fprintf(os.Stderr, "Usage: %s dupes <directory> [--min-lines N] [--format json|text] [options]\n", os.Args[0])
+		os.Exit(exitUsage)
+	}
 
-	// Generate-only mode (with -fp flag)
+	// Git diff scans default to the current directory
+	path := "."
+	if compareMode && *cohort != "" {
+		path = *cohort
+	} else if compareMode {
+		path = subArgs[0]
+	} else if dupesMode {
+		path = subArgs[0]
+	} else if indexMode {
+		path = "."
+	} else if flag.NArg() == 1 {
+		path = flag.Arg(0)
+	} else if flag.NArg() != 0 || *gitDiff == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <file|directory|file.wfp>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] --git-diff <base>..<head> [directory]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] -              (code or WFP from stdin)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s snippet [--lang <language>] [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare --cohort <directory> [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare <target> <reference> [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s dupes <directory> [--format json|text]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s index build <directory|file.wfp>... -o corpus.idx\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s index add corpus.idx <directory|file.wfp>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s scan --index corpus.idx <file|directory|file.wfp>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s --version\n\nOptions:\n", os.Args[0])
+		flag.PrintDefaults()
+		os.Exit(exitUsage)
+	}
+
+	// Determine input type ("-" reads from stdin)
+	var fileInfo os.FileInfo
+	if path != "-" {
+		fileInfo, err = os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitScanError)
+		}
+	}
+
+	if *fpThreads < 1 {
+		*fpThreads = *numThreads
+	}
+
+	isWFPFile := fileInfo != nil && !fileInfo.IsDir() && strings.HasSuffix(strings.ToLower(path), ".wfp")
+
+	// Project settings: file filters, identified components. The default settings file is
+	// looked up in the scanned directory, or in the directory of the scanned file.
+	if *settingsFile == "" {
+		root := "."
+		if fileInfo != nil && fileInfo.IsDir() {
+			root = path
+		} else if fileInfo != nil {
+			root = filepath.Dir(path)
+		}
+		name := filepath.Join(root, pkg.SettingsFileName)
+		if _, err := os.Stat(name); err == nil {
+			*settingsFile = name
+		}
+	}
+
+	// File filters from the settings file, extended by the command line
+	if err := pkg.LoadFilters(*settingsFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading settings: %v\n", err)
+		os.Exit(exitScanError)
+	}
+	filters := models.Filters{Include: includes, Exclude: excludes, MaxFileSize: *maxFileSize}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "min-file-size" {
+			filters.MinFileSize = minFileSize
+		}
+	})
+	for _, exts := range skipExts {
+		filters.SkipExtensions = append(filters.SkipExtensions, strings.Split(exts, ",")...)
+	}
+	if err := pkg.ApplyFilters(filters); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitUsage)
+	}
+
+	wfpOpts := pkg.WFPOptions{
+		Threads:        *fpThreads,
+		HPSM:           *hpsm,
+		NoIgnore:       *noIgnore,
+		PathPrefix:     *pathPrefix,
+		AbsolutePaths:  *absolutePaths,
+		FollowSymlinks: *followSymlinks,
+		Warnings:       os.Stderr,
+		Archives:       *archives,
+		ArchiveDepth:   *archiveDepth,
+		ArchiveMaxSize: *archiveMaxSize,
+
+		IncludeGenerated: *includeGenerated,
+		GitDiff:          *gitDiff,
+		ChangedLinesOnly: *changedLinesOnly,
+		Mode:             wfpMode,
+	}
+
+	if indexMode {
+		os.Exit(runIndex(ctx, subArgs, *outputFile, wfpOpts, modeSet))
+	}
+
+	// The fingerprints of an index scan default to the mode of the index
+	var corpus *pkg.CorpusIndex
+	corpusName := *indexFile
+	if *indexFile != "" && !*generateMode && !compareMode {
+		if corpus, err = pkg.LoadIndex(*indexFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading index: %v\n", err)
+			os.Exit(exitScanError)
+		}
+		if !modeSet {
+			wfpOpts.Mode = corpus.Mode
+		}
+	}
+
+	// Starter code is fingerprinted with the same options as the scanned code (not needed by -fp)
 	if *generateMode {
-		var wfp string
-		if fileInfo.IsDir() {
-			wfp, err = pkg.GenerateWFPFromDirectory(path)
-		} else {
-			wfp, err = pkg.GenerateWFPFromFile(path)
+		*templateDir = ""
+	}
+	if err := pkg.LoadTemplate(ctx, *templateDir, wfpOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitScanError)
+	}
+
+	if compareMode && *cohort != "" {
+		os.Exit(runCompare(ctx, *cohort, *graphFile, pkg.CompareOptions{
+			WFP:              wfpOpts,
+			MinHits:          *minHits,
+			MaxSubmissions:   *maxSubmissions,
+			ClusterThreshold: *clusterThreshold,
+		}))
+	}
+	if dupesMode {
+		os.Exit(runDupes(ctx, path, *format, pkg.DupesOptions{
+			WFP:      wfpOpts,
+			MinHits:  *minHits,
+			MinLines: *minLines,
+		}))
+	}
+	// Two-project comparison: the reference tree is indexed in memory and the target scanned
+	// against it. The git diff options select target files, the whole reference is indexed.
+	if compareMode {
+		corpusName = subArgs[1]
+		fmt.Fprintf(os.Stderr, "Fingerprinting %s...\n", corpusName)
+		refOpts := wfpOpts
+		refOpts.GitDiff, refOpts.ChangedLinesOnly = "", false
+		corpus = pkg.NewIndex(wfpOpts.Mode)
+		if err := corpus.Add(ctx, []string{corpusName}, refOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitScanError)
 		}
+	}
 
+	// Code or a WFP read from stdin is scanned from a temporary WFP file
+	var stdinWFP string
+	if path == "-" {
+		wfp, err := readStdinWFP(*lang, wfpOpts)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error generating WFP: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitScanError)
+		}
+		if *generateMode {
+			if *outputFile == "" {
+				fmt.Print(wfp)
+			} else if err := os.WriteFile(*outputFile, []byte(wfp), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+				os.Exit(exitScanError)
+			}
+			return
 		}
 
+		stdinFile, err := os.CreateTemp("", "stdin-*.wfp")
+		if err == nil {
+			_, err = stdinFile.WriteString(wfp)
+			if closeErr := stdinFile.Close(); err == nil {
+				err = closeErr
+			}
+			stdinWFP = stdinFile.Name()
+			defer os.Remove(stdinWFP)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating temporary file: %v\n", err)
+			os.Exit(exitScanError)
+		}
+		path = stdinWFP
+		isWFPFile = true
+	}
+
+	// Generate-only mode (with -fp flag)
+	if *generateMode {
+		opts := wfpOpts
+
 		// Write output
 		if *outputFile != "" {
-			err = os.WriteFile(*outputFile, []byte(wfp), 0644)
+			out, err := os.Create(*outputFile)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
-				os.Exit(1)
+				os.Exit(exitScanError)
+			}
+			err = generateWFP(ctx, path, out, opts)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(*outputFile)
+				fmt.Fprintf(os.Stderr, "Error generating WFP: %v\n", err)
+				os.Exit(exitScanError)
 			}
 			fmt.Fprintf(os.Stderr, "WFP successfully generated at: %s\n", *outputFile)
-		} else {
-			fmt.Print(wfp)
+		} else if err := generateWFP(ctx, path, os.Stdout, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating WFP: %v\n", err)
+			os.Exit(exitScanError)
 		}
 		return
 	}
 
+	// Load declared components from the project settings file
+	if err := pkg.LoadIdentifications(*settingsFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading settings: %v\n", err)
+		os.Exit(exitScanError)
+	}
+	if err := pkg.LoadLicenseMap(*licenseMapFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading license map: %v\n", err)
+		os.Exit(exitScanError)
+	}
+	var policy *models.Policy
+	if *policyFile != "" {
+		policy, err = pkg.LoadPolicy(*policyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading policy: %v\n", err)
+			os.Exit(exitScanError)
+		}
+	}
+
 	// Scan mode
 	var wfpFile string
 	var tempFile *os.File
@@ -117,63 +458,101 @@ func main() {
 		// It's a .wfp file, use it directly
 		wfpFile = path
 	} else {
-		// Not a .wfp file, generate temporary WFP
-		var wfp string
-		if fileInfo.IsDir() {
-			fmt.Fprintf(os.Stderr, "Generating WFP...\n")
-			progress := &progressWriter{}
-			wfp, err = pkg.GenerateWFPFromDirectoryWithProgress(path, progress)
-			if progress.bar != nil {
-				progress.bar.Finish()
-				fmt.Fprintln(os.Stderr)
-			}
-		} else {
-			wfp, err = pkg.GenerateWFPFromFile(path)
-		}
-
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating WFP: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Create temporary file
+		// Not a .wfp file, stream the WFP to a temporary file
 		tempFile, err = os.CreateTemp("", "scan-*.wfp")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating temporary file: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitScanError)
 		}
 		defer os.Remove(tempFile.Name())
 		defer tempFile.Close()
 
-		_, err = tempFile.WriteString(wfp)
+		opts := wfpOpts
+		var progress *progressWriter
+		if fileInfo.IsDir() {
+			fmt.Fprintf(os.Stderr, "Generating WFP...\n")
+			progress = &progressWriter{}
+			opts.Progress = progress
+		}
+		err = generateWFP(ctx, path, tempFile, opts)
+		if progress != nil && progress.bar != nil {
+			progress.bar.Finish()
+			fmt.Fprintln(os.Stderr)
+		}
+		if err == nil {
+			err = tempFile.Close()
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing temporary WFP: %v\n", err)
-			os.Exit(1)
+			os.Remove(tempFile.Name())
+			fmt.Fprintf(os.Stderr, "Error generating WFP: %v\n", err)
+			os.Exit(exitScanError)
 		}
-		tempFile.Close()
 
 		wfpFile = tempFile.Name()
 	}
 
 	// Scan WFP file
-	fmt.Fprintf(os.Stderr, "Scanning files with %d threads...\n", *numThreads)
 	progress := &progressWriter{}
-	results, err := pkg.ScanWFPFile(kbName, wfpFile, *minHits, progress, *numThreads)
+	var results map[string][]*models.MatchResult
+	if compareMode {
+		fmt.Fprintf(os.Stderr, "Comparing files with %s...\n", corpusName)
+		results, err = pkg.ScanIndexAll(corpus, wfpFile, *minHits, progress)
+	} else if corpus != nil {
+		fmt.Fprintf(os.Stderr, "Scanning files against %s...\n", corpusName)
+		results, err = pkg.ScanIndex(corpus, wfpFile, *minHits, progress)
+	} else {
+		fmt.Fprintf(os.Stderr, "Scanning files with %d threads...\n", *numThreads)
+		results, err = pkg.ScanWFPFile(kbName, wfpFile, *minHits, progress, *numThreads)
+	}
 	if progress.bar != nil {
 		progress.bar.Finish()
 		fmt.Fprintln(os.Stderr)
 	}
+	// The temporary WFP is no longer needed (policy exit codes skip deferred calls)
+	if tempFile != nil {
+		os.Remove(tempFile.Name())
+	}
+	if stdinWFP != "" {
+		os.Remove(stdinWFP)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error scanning WFP: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitScanError)
+	}
+
+	// Policy is evaluated over every match, including the ones hidden from the output
+	var verdict *models.PolicyVerdict
+	if policy != nil {
+		verdict = pkg.EvaluatePolicy(policy, results)
+	}
+
+	if !*showIdentified {
+		pkg.FilterIdentified(results)
 	}
 
 	// Convert to JSON and display
-	jsonOutput, err := json.MarshalIndent(results, "", "  ")
+	var output interface{} = results
+	if verdict != nil {
+		output = struct {
+			Results map[string][]*models.MatchResult `json:"results"`
+			Policy  *models.PolicyVerdict            `json:"policy"`
+		}{results, verdict}
+	}
+	jsonOutput, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating JSON: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitScanError)
 	}
 
 	fmt.Println(string(jsonOutput))
+
+	if verdict != nil {
+		fmt.Fprintf(os.Stderr, "Policy verdict: %s\n", verdict.Status)
+		switch verdict.Status {
+		case pkg.PolicyFail:
+			os.Exit(exitFail)
+		case pkg.PolicyWarn:
+			os.Exit(exitWarn)
+		}
+	}
 }
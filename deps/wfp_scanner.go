@@ -64,9 +64,9 @@ func ParseWFPFileForMD5(filepath string, targetMD5 string) (*models.WFPData, err
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		// Parse file header line
+		// Parse file header line (file=md5,size,path; the path may contain commas)
 		if strings.HasPrefix(line, "file=") {
-			parts := strings.Split(strings.TrimPrefix(line, "file="), ",")
+			parts := strings.SplitN(strings.TrimPrefix(line, "file="), ",", 3)
 			if len(parts) < 3 {
 				continue
 			}
@@ -90,10 +90,10 @@ func ParseWFPFileForMD5(filepath string, targetMD5 string) (*models.WFPData, err
 				}
 				copy(wfpData.MD5[:], md5Bytes)
 
-				// Parse total lines
-				wfpData.TotalLines, err = strconv.Atoi(parts[1])
+				// Parse file size
+				wfpData.Size, err = strconv.Atoi(parts[1])
 				if err != nil {
-					return nil, fmt.Errorf("failed to parse total lines: %v", err)
+					return nil, fmt.Errorf("failed to parse file size: %v", err)
 				}
 
 				// Parse file path
@@ -103,6 +103,12 @@ func ParseWFPFileForMD5(filepath string, targetMD5 string) (*models.WFPData, err
 				processingTarget = false
 			}
 
+		} else if strings.HasPrefix(line, "fh2=") && processingTarget {
+			wfpData.FH2 = strings.TrimPrefix(line, "fh2=")
+		} else if strings.HasPrefix(line, "hpsm=") && processingTarget {
+			wfpData.HPSM = strings.TrimPrefix(line, "hpsm=")
+		} else if strings.HasPrefix(line, "mode=") && processingTarget {
+			wfpData.Mode = strings.TrimPrefix(line, "mode=")
 		} else if strings.Contains(line, "=") && processingTarget {
 			// Only parse hashes if we are processing the target file
 			// Parse hash lines (format: line_number=hash1,hash2,...)
@@ -111,11 +117,17 @@ func ParseWFPFileForMD5(filepath string, targetMD5 string) (*models.WFPData, err
 				continue
 			}
 
+			// Other optional sections are not used by the snippet scanner
 			lineNum, err := strconv.Atoi(parts[0])
 			if err != nil {
 				continue
 			}
 
+			// Total lines is the last line holding hashes
+			if lineNum > wfpData.TotalLines {
+				wfpData.TotalLines = lineNum
+			}
+
 			// Parse hashes for this line
 			hashStrings := strings.Split(parts[1], ",")
 			for _, hashStr := range hashStrings {
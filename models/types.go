@@ -9,22 +9,193 @@ package models
 type WFPData struct {
 	MD5        [16]byte
 	MD5Hex     string // Hexadecimal version of MD5 for compatibility
-	TotalLines int
+	Size       int    // File size in bytes (file= header)
+	TotalLines int    // Last line number holding snippet hashes
 	FilePath   string
+	FH2        string // MD5 of the file with opposite line endings (fh2= line)
+	HPSM       string // High Precision Snippet Matching line hashes (hpsm= line)
+	Mode       string // Normalization mode (mode= line), empty for KB compatible fingerprints
 	Hashes     []uint32
 	Lines      []uint32
 }
 
 // MatchResult represents a match result (for JSON output)
 type MatchResult struct {
-	MatchType     string  `json:"match_type"`
-	TargetLines   string  `json:"target_lines,omitempty"`
-	SourceLines   string  `json:"ref_file_lines,omitempty"`
-	Instances     int     `json:"instances"`
-	ReferenceURL  string  `json:"reference_url"`
-	ReferenceFile string  `json:"reference_file"`
-	Hits          int     `json:"-"` // For internal use (not exported in JSON)
-	Ranges        []Range `json:"-"` // For internal use (not exported in JSON)
+	MatchType     string          `json:"match_type"`
+	TargetLines   string          `json:"target_lines,omitempty"`
+	SourceLines   string          `json:"ref_file_lines,omitempty"`
+	Instances     int             `json:"instances"`
+	ReferenceURL  string          `json:"reference_url"`
+	ReferenceFile string          `json:"reference_file"`
+	Purl          string          `json:"purl,omitempty"` // Package URL derived from the reference URL
+	Component     string          `json:"component,omitempty"`
+	Vendor        string          `json:"vendor,omitempty"`
+	Version       string          `json:"version,omitempty"`
+	Status        string          `json:"status,omitempty"` // "identified" when covered by a declared component
+	Identified    *Identification `json:"identification,omitempty"`
+	Declared      *Dependency     `json:"declared_dependency,omitempty"` // Manifest dependency the reference URL maps to
+	Licenses      []string        `json:"licenses,omitempty"`            // SPDX identifiers of the referenced component
+	Copyleft      bool            `json:"copyleft,omitempty"`            // True if any license belongs to a copyleft family
+	Coverage      float64         `json:"coverage,omitempty"`            // Percentage of the scanned file lines covered by the match
+	ReferenceMD5  string          `json:"-"`                             // For internal use (not exported in JSON)
+	Hits          int             `json:"-"`                             // For internal use (not exported in JSON)
+	Ranges        []Range         `json:"-"`                             // For internal use (not exported in JSON)
+}
+
+// Settings represents a project settings file (plagicheck.json)
+type Settings struct {
+	Identified []Identification `json:"identified"`
+	Filters    *Filters         `json:"filters,omitempty"`
+}
+
+// Filters selects the files fingerprinted during a directory walk.
+// Patterns are globs over the path relative to the scanned directory, '**' matches directories.
+type Filters struct {
+	Include          []string `json:"include,omitempty"`           // If set, only matching files are fingerprinted
+	Exclude          []string `json:"exclude,omitempty"`           // Matching files and directories are skipped
+	SkipExtensions   []string `json:"skip_extensions,omitempty"`   // Extensions added to the built-in skip list
+	AllowExtensions  []string `json:"allow_extensions,omitempty"`  // Extensions removed from the built-in skip lists
+	OverrideDefaults bool     `json:"override_defaults,omitempty"` // Discard the built-in extension lists
+	MinFileSize      *int64   `json:"min_file_size,omitempty"`     // Files of this size or smaller are skipped (default 100, 0 keeps every file)
+	MaxFileSize      int64    `json:"max_file_size,omitempty"`     // Larger files are skipped (0 means no limit)
+}
+
+// Identification declares a component whose matches are expected and already reviewed.
+// At least one of URL, Purl, Path or MD5 must be set; all the set fields must match.
+type Identification struct {
+	URL      string `json:"url,omitempty"`  // Reference URL pattern, '*' matches any sequence
+	Purl     string `json:"purl,omitempty"` // Package URL pattern, '*' matches any sequence
+	Path     string `json:"path,omitempty"` // Glob over the scanned file path, '**' matches directories
+	MD5      string `json:"md5,omitempty"`  // MD5 of the scanned file or of the matched reference file
+	Reviewer string `json:"reviewer,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+// Dependency represents a package declared in a project manifest (go.mod, package.json, ...)
+type Dependency struct {
+	Ecosystem string `json:"ecosystem"` // golang, npm, pypi, maven or cargo
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Manifest  string `json:"manifest"`
+}
+
+// Policy represents a policy file evaluated after a scan
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule selects matches with its conditions and triggers its action when
+// the number of selected matches exceeds MaxMatches
+type PolicyRule struct {
+	Name         string  `json:"name"`
+	Action       string  `json:"action"`                  // "fail" or "warn"
+	MatchType    string  `json:"match_type,omitempty"`    // full_file or code_snippet (default: any)
+	Copyleft     bool    `json:"copyleft,omitempty"`      // Only matches with a copyleft license
+	MinCoverage  float64 `json:"min_coverage,omitempty"`  // Only matches covering more than this percentage of the file
+	MaxMatches   int     `json:"max_matches,omitempty"`   // Number of selected matches tolerated (default: 0)
+	IncludeKnown bool    `json:"include_known,omitempty"` // Also select identified matches and declared dependencies
+}
+
+// PolicyVerdict contains the outcome of evaluating a policy
+type PolicyVerdict struct {
+	Status     string            `json:"status"` // "pass", "warn" or "fail"
+	Violations []PolicyViolation `json:"violations,omitempty"`
+}
+
+// PolicyViolation describes a triggered policy rule
+type PolicyViolation struct {
+	Rule    string   `json:"rule"`
+	Action  string   `json:"action"`
+	Matches int      `json:"matches"`
+	Files   []string `json:"files"`
+}
+
+// CohortReport represents the result of comparing a cohort of submissions with each other
+type CohortReport struct {
+	Submissions      []Submission  `json:"submissions"`
+	Pairs            []SimilarPair `json:"pairs"`
+	ClusterThreshold float64       `json:"cluster_threshold"` // Similarity linking submissions into clusters
+	Clusters         []Cluster     `json:"clusters"`
+}
+
+// Submission represents a compared set of files (a cohort submission)
+type Submission struct {
+	Name   string `json:"name"`
+	Files  int    `json:"files"`
+	Hashes int    `json:"hashes"` // Distinct hashes, common hashes excluded
+}
+
+// SimilarPair represents two submissions sharing code
+type SimilarPair struct {
+	A            string      `json:"a"`
+	B            string      `json:"b"`
+	SimilarityA  float64     `json:"similarity_a"` // Percentage of the hashes of A found in B
+	SimilarityB  float64     `json:"similarity_b"` // Percentage of the hashes of B found in A
+	Hashes       int         `json:"shared_hashes"`
+	SharedLinesA int         `json:"shared_lines_a"` // Lines of A covered by the matches
+	SharedLinesB int         `json:"shared_lines_b"`
+	Matches      []FileMatch `json:"matches"`
+}
+
+// Cluster represents submissions linked by pairs at or above the cluster threshold
+type Cluster struct {
+	ID            int      `json:"id"`
+	Submissions   []string `json:"submissions"`
+	MaxSimilarity float64  `json:"max_similarity"`
+}
+
+// SimilarityGraph represents the similarity graph of a cohort (JSON export)
+type SimilarityGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode represents a submission of the similarity graph
+type GraphNode struct {
+	ID      string `json:"id"`
+	Files   int    `json:"files"`
+	Hashes  int    `json:"hashes"`
+	Cluster int    `json:"cluster,omitempty"` // Cluster ID, 0 when not clustered
+}
+
+// GraphEdge represents a similar pair of the similarity graph
+type GraphEdge struct {
+	Source       string  `json:"source"`
+	Target       string  `json:"target"`
+	Weight       float64 `json:"weight"` // Highest similarity of the pair
+	SharedHashes int     `json:"shared_hashes"`
+	SharedLinesA int     `json:"shared_lines_source"`
+	SharedLinesB int     `json:"shared_lines_target"`
+}
+
+// FileMatch represents the code shared by a file of each side of a pair
+type FileMatch struct {
+	FileA  string `json:"file_a"`
+	FileB  string `json:"file_b"`
+	LinesA string `json:"lines_a"`
+	LinesB string `json:"lines_b"`
+	Hashes int    `json:"shared_hashes"`
+}
+
+// DupesReport represents the duplicated code found within a tree
+type DupesReport struct {
+	Files  int         `json:"files"` // Fingerprinted files
+	Groups []DupeGroup `json:"groups"`
+}
+
+// DupeGroup represents a block of code found at several locations
+type DupeGroup struct {
+	ID        int            `json:"id"`
+	Hashes    int            `json:"shared_hashes"`
+	Lines     int            `json:"lines"` // Shortest span of the locations
+	Locations []DupeLocation `json:"locations"`
+}
+
+// DupeLocation represents a copy of a duplicated block
+type DupeLocation struct {
+	File  string `json:"file"`
+	Lines string `json:"lines"` // Line ranges ("12-40")
+	Span  int    `json:"span"`  // Lines covered by the ranges
 }
 
 // MatchInfo contains information about an individual match (internal use)
@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Default limits when descending into archives
+const (
+	DefaultArchiveDepth      = 3       // Archives nested in archives up to 3 levels
+	DefaultArchiveMaxSize    = 1 << 30 // 1 GiB of uncompressed data per archive
+	DefaultArchiveMemberSize = 8 << 20 // 8 MiB per member file, unless the maximum file size is lower
+)
+
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveZip
+	archiveTar
+	archiveTarGz
+	archiveTarBz2
+)
+
+// Archive file suffixes, zip based formats include Java, Android, Python and NuGet packages
+var archiveSuffixes = []struct {
+	suffix string
+	kind   archiveKind
+}{
+	{".zip", archiveZip}, {".jar", archiveZip}, {".war", archiveZip}, {".ear", archiveZip},
+	{".aar", archiveZip}, {".whl", archiveZip}, {".nupkg", archiveZip},
+	{".tar", archiveTar},
+	{".tar.gz", archiveTarGz}, {".tgz", archiveTarGz},
+	{".tar.bz2", archiveTarBz2}, {".tbz2", archiveTarBz2}, {".tbz", archiveTarBz2},
+}
+
+var scanArchives bool
+
+var errArchiveLimit = errors.New("archive size limit reached")
+
+// archiveKindOf returns the archive format of a file name, or archiveNone
+func archiveKindOf(name string) archiveKind {
+	lower := strings.ToLower(name)
+	for _, a := range archiveSuffixes {
+		if strings.HasSuffix(lower, a.suffix) {
+			return a.kind
+		}
+	}
+	return archiveNone
+}
+
+// archiveWalker fingerprints the members of an archive and of the archives nested in it
+type archiveWalker struct {
+	opts      WFPOptions
+	maxDepth  int
+	budget    int64 // Uncompressed bytes left to read
+	memberMax int64 // Largest member file read into memory
+	out       strings.Builder
+}
+
+// fingerprintArchive generates the WFP of the members of an archive without extracting it.
+// Members are recorded as <wfpPath>!/<member>; relPath is the archive path used for file filters.
+func fingerprintArchive(filePath, relPath, wfpPath string, opts WFPOptions) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		DebugLog("Skipping %s: %v\n", filePath, err)
+		return ""
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		DebugLog("Skipping %s: %v\n", filePath, err)
+		return ""
+	}
+
+	a := &archiveWalker{opts: opts, maxDepth: opts.ArchiveDepth, budget: opts.ArchiveMaxSize}
+	if a.maxDepth < 1 {
+		a.maxDepth = DefaultArchiveDepth
+	}
+	if a.budget < 1 {
+		a.budget = DefaultArchiveMaxSize
+	}
+	maxSize := a.budget
+	a.memberMax = DefaultArchiveMemberSize
+	if fileFilters.MaxFileSize > 0 && fileFilters.MaxFileSize < a.memberMax {
+		a.memberMax = fileFilters.MaxFileSize
+	}
+
+	err = a.walk(archiveKindOf(filePath), file, info.Size(), relPath, wfpPath, 1)
+	if errors.Is(err, errArchiveLimit) {
+		DebugLog("Archive %s exceeds %d uncompressed bytes, remaining members skipped\n", filePath, maxSize)
+		if opts.Warnings != nil {
+			fmt.Fprintf(opts.Warnings, "Warning: archive %s exceeds the size limit, remaining members skipped\n", filePath)
+		}
+	}
+	return a.out.String()
+}
+
+// walk fingerprints the members of an archive read from r. Only the size limit aborts the walk:
+// corrupt archives are skipped with a debug message.
+func (a *archiveWalker) walk(kind archiveKind, r io.Reader, size int64, relPath, wfpPath string, depth int) error {
+	if kind == archiveZip {
+		ra, ok := r.(io.ReaderAt)
+		if !ok {
+			return fmt.Errorf("zip archive %s is not seekable", wfpPath)
+		}
+		zr, err := zip.NewReader(ra, size)
+		if err != nil {
+			DebugLog("Skipping %s: %v\n", wfpPath, err)
+			return nil
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				DebugLog("Skipping %s!/%s: %v\n", wfpPath, f.Name, err)
+				continue
+			}
+			err = a.member(f.Name, int64(f.UncompressedSize64), rc, relPath, wfpPath, depth)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch kind {
+	case archiveTarGz:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			DebugLog("Skipping %s: %v\n", wfpPath, err)
+			return nil
+		}
+		defer gz.Close()
+		r = gz
+	case archiveTarBz2:
+		r = bzip2.NewReader(r)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			DebugLog("Skipping the rest of %s: %v\n", wfpPath, err)
+			return nil
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := a.member(hdr.Name, hdr.Size, tr, relPath, wfpPath, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// member fingerprints an archive member, descending into nested archives
+func (a *archiveWalker) member(name string, size int64, r io.Reader, relPath, wfpPath string, depth int) error {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+	memberRel := relPath + "!/" + name
+	memberPath := wfpPath + "!/" + name
+
+	// Skip hidden files and directories, and macOS resource forks
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") || segment == "__MACOSX" {
+			DebugLog("Skipping %s: hidden file\n", memberPath)
+			return nil
+		}
+	}
+
+	kind := archiveKindOf(name)
+	if kind != archiveNone {
+		if depth >= a.maxDepth {
+			DebugLog("Skipping %s: archive nested deeper than %d levels\n", memberPath, a.maxDepth)
+			return nil
+		}
+		if pattern := excludedPath(memberRel); pattern != "" {
+			DebugLog("Skipping %s: excluded by %s\n", memberPath, pattern)
+			return nil
+		}
+	} else {
+		if reason := fileSkipReason(memberRel, size); reason != "" {
+			DebugLog("Skipping %s: %s\n", memberPath, reason)
+			return nil
+		}
+		if isMinified(path.Base(name)) {
+			DebugLog("Skipping minimized file: %s\n", memberPath)
+			return nil
+		}
+	}
+
+	// Headers may understate the size, the read is bounded by the remaining budget and, for
+	// member files, by the member limit
+	limit := a.budget
+	if kind == archiveNone {
+		if size > a.memberMax {
+			DebugLog("Skipping %s: member larger than %d bytes\n", memberPath, a.memberMax)
+			return nil
+		}
+		limit = min(limit, a.memberMax)
+	}
+	if size > a.budget {
+		return errArchiveLimit
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		DebugLog("Skipping %s: %v\n", memberPath, err)
+		return nil
+	}
+	if int64(len(data)) > a.budget {
+		return errArchiveLimit
+	}
+	a.budget -= int64(len(data))
+	if int64(len(data)) > a.memberMax && kind == archiveNone {
+		DebugLog("Skipping %s: member larger than %d bytes\n", memberPath, a.memberMax)
+		return nil
+	}
+
+	if kind != archiveNone {
+		return a.walk(kind, bytes.NewReader(data), int64(len(data)), memberRel, memberPath, depth+1)
+	}
+	text, encoding := decodeText(data)
+	if reason := contentSkipReason(text, a.opts); reason != "" {
+		DebugLog("Skipping %s: %s\n", memberPath, reason)
+		return nil
+	}
+	a.out.WriteString(fingerprintText(memberPath, data, text, encoding, a.opts))
+	return nil
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var archiveSource = strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+
+// zipBytes builds a zip archive with the given members
+func zipBytes(t *testing.T, members map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range members {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create zip member: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzBytes builds a gzipped tar archive with the given members
+func tarGzBytes(t *testing.T, members map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range members {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestArchiveKindOf(t *testing.T) {
+	tests := map[string]archiveKind{
+		"vendor.zip":        archiveZip,
+		"lib/Library.JAR":   archiveZip,
+		"pkg-1.0.tar.gz":    archiveTarGz,
+		"pkg.tgz":           archiveTarGz,
+		"pkg.tar.bz2":       archiveTarBz2,
+		"pkg.tar":           archiveTar,
+		"main.c":            archiveNone,
+		"notes.gz":          archiveNone,
+		"archive.zip.bak.c": archiveNone,
+	}
+	for name, want := range tests {
+		if got := archiveKindOf(name); got != want {
+			t.Errorf("archiveKindOf(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestGenerateWFP_Archives(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := tarGzBytes(t, map[string]string{"pkg/inner.c": archiveSource + "// inner\n"})
+	vendor := zipBytes(t, map[string]string{
+		"src/foo.c":       archiveSource,
+		"src/tiny.c":      "int x;\n",
+		".git/config.c":   archiveSource,
+		"libs/dep.tar.gz": string(nested),
+	})
+	writeFile(t, filepath.Join(tmpDir, "vendor.zip"), string(vendor))
+	writeFile(t, filepath.Join(tmpDir, "main.c"), archiveSource+"// main\n")
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 2, Archives: true}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{",main.c\n", ",vendor.zip!/src/foo.c\n", ",vendor.zip!/libs/dep.tar.gz!/pkg/inner.c\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in WFP, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"tiny.c", "config.c"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected %s to be skipped", unwanted)
+		}
+	}
+
+	// Archives are skipped unless enabled
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "vendor.zip") {
+		t.Error("archives should be skipped by default")
+	}
+}
+
+func TestFingerprintArchive_Limits(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := zipBytes(t, map[string]string{"deep.c": archiveSource})
+	archive := filepath.Join(tmpDir, "outer.zip")
+	writeFile(t, archive, string(zipBytes(t, map[string]string{
+		"a.c":       archiveSource,
+		"inner.jar": string(nested),
+	})))
+
+	wfp := fingerprintArchive(archive, "outer.zip", "outer.zip", WFPOptions{ArchiveDepth: 1})
+	if !strings.Contains(wfp, "outer.zip!/a.c") || strings.Contains(wfp, "deep.c") {
+		t.Errorf("depth 1 should only fingerprint the direct members, got:\n%s", wfp)
+	}
+
+	var warnings bytes.Buffer
+	wfp = fingerprintArchive(archive, "outer.zip", "outer.zip", WFPOptions{ArchiveMaxSize: 100, Warnings: &warnings})
+	if strings.Contains(wfp, "file=") {
+		t.Errorf("no member should fit in 100 bytes, got:\n%s", wfp)
+	}
+	if !strings.Contains(warnings.String(), "size limit") {
+		t.Errorf("expected a size limit warning, got %q", warnings.String())
+	}
+}
+
+func TestFingerprintArchive_MemberLimit(t *testing.T) {
+	LoadFilters("")
+	tmpDir := t.TempDir()
+	archive := filepath.Join(tmpDir, "dump.tar.gz")
+	writeFile(t, archive, string(tarGzBytes(t, map[string]string{
+		"big.c":   strings.Repeat(archiveSource, DefaultArchiveMemberSize/len(archiveSource)+1),
+		"small.c": archiveSource,
+	})))
+
+	// The oversized member is skipped, the rest of the archive is still fingerprinted
+	var warnings bytes.Buffer
+	wfp := fingerprintArchive(archive, "dump.tar.gz", "dump.tar.gz", WFPOptions{Warnings: &warnings})
+	if !strings.Contains(wfp, "dump.tar.gz!/small.c") || strings.Contains(wfp, "big.c") || warnings.Len() != 0 {
+		t.Errorf("expected small.c only and no warning, got %q:\n%s", warnings.String(), wfp)
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// Content heuristics thresholds
+const (
+	contentSniffSize     = 8000 // Bytes inspected for binary content (as git does)
+	maxNonPrintableRatio = 0.30 // Higher ratios of control characters denote binary content
+	minifiedLineLength   = 300  // Average line length of minified bundles
+	minifiedMinSize      = 1024 // Smaller files are not considered minified bundles
+	generatedHeaderLines = 20   // Lines searched for generated code markers
+)
+
+// generatedMarker matches the markers generators put in a comment line of their output: the
+// Go convention "// Code generated ... DO NOT EDIT." and "@generated" (protobuf, Thrift, Rust
+// tooling). Looser markers ("auto-generated") also appear in hand-written headers and are
+// not honoured.
+var generatedMarker = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.\r?$|^\s*(?://+|#+|/?\*+|--|;+).*@generated\b`)
+
+// contentSkipReason returns why contents should not be fingerprinted (binary, minified or
+// generated code), or "" when they look like regular source code. The heuristics apply to
+// the text returned by decodeText, as UTF-16 contents are full of NUL bytes.
+func contentSkipReason(data []byte, opts WFPOptions) string {
+	sniff := data
+	if len(sniff) > contentSniffSize {
+		sniff = sniff[:contentSniffSize]
+	}
+
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return "binary content (NUL bytes)"
+	}
+	nonPrintable := 0
+	for _, b := range sniff {
+		if (b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f') || b == 0x7f {
+			nonPrintable++
+		}
+	}
+	if len(sniff) > 0 {
+		if ratio := float64(nonPrintable) / float64(len(sniff)); ratio > maxNonPrintableRatio {
+			return fmt.Sprintf("binary content (%.0f%% non-printable)", ratio*100)
+		}
+	}
+
+	if len(data) >= minifiedMinSize {
+		lines := bytes.Count(data, []byte("\n"))
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			lines++
+		}
+		if avg := len(data) / lines; avg > minifiedLineLength {
+			return fmt.Sprintf("minified content (average line length %d)", avg)
+		}
+	}
+
+	if !opts.IncludeGenerated {
+		head := data
+		for i, n := 0, 0; i < len(head); i++ {
+			if head[i] == '\n' {
+				n++
+				if n == generatedHeaderLines {
+					head = head[:i]
+					break
+				}
+			}
+		}
+		if m := generatedMarker.Find(head); m != nil {
+			return fmt.Sprintf("generated code (%q marker)", m)
+		}
+	}
+	return ""
+}
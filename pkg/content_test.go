@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentSkipReason(t *testing.T) {
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 30)
+	binary := bytes.Repeat([]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x03, 0x04, 0x05}, 100)
+
+	tests := []struct {
+		name   string
+		data   string
+		opts   WFPOptions
+		reason string
+	}{
+		{"source", source, WFPOptions{}, ""},
+		{"NUL bytes", "int x;\x00\x00\x00" + source, WFPOptions{}, "binary content (NUL bytes)"},
+		{"control characters", string(binary), WFPOptions{}, "binary content"},
+		{"minified", "var a=1;" + strings.Repeat("function f(a){return a*2};", 200) + "\n", WFPOptions{}, "minified content"},
+		{"long file with short lines", strings.Repeat("x = 1\n", 2000), WFPOptions{}, ""},
+		{"go generated", "// Code generated by protoc-gen-go. DO NOT EDIT.\n// source: api.proto\n\npackage api\n" + source, WFPOptions{}, "generated code"},
+		{"@generated", "/*\n * @generated by thrift\n */\n" + source, WFPOptions{}, "generated code"},
+		{"auto-generated", "# This file is auto-generated from schema.yaml\n" + source, WFPOptions{}, ""},
+		{"autogenerated mention", "/* Hand-written; autogenerated stubs are in gen/ */\n" + source, WFPOptions{}, ""},
+		{"@generated in code", "const char *tag = \"@generated\";\n" + source, WFPOptions{}, ""},
+		{"go generated crlf", "// Code generated by mockgen. DO NOT EDIT.\r\n" + source, WFPOptions{}, "generated code"},
+		{"go marker not at line start", "/* Code generated by hand. DO NOT EDIT. */\n" + source, WFPOptions{}, ""},
+		{"marker after the header", source + "// Code generated by hand. DO NOT EDIT.\n", WFPOptions{}, ""},
+		{"generated included", "// Code generated by stringer. DO NOT EDIT.\n" + source, WFPOptions{IncludeGenerated: true}, ""},
+		{"prose mention", "// Parses files; code generated elsewhere may be edited freely\n" + source, WFPOptions{}, ""},
+	}
+
+	for _, tt := range tests {
+		reason := contentSkipReason([]byte(tt.data), tt.opts)
+		if tt.reason == "" && reason != "" {
+			t.Errorf("%s: unexpected reason %q", tt.name, reason)
+		} else if !strings.HasPrefix(reason, tt.reason) {
+			t.Errorf("%s: reason = %q, want %q", tt.name, reason, tt.reason)
+		}
+	}
+}
+
+func TestGenerateWFP_ContentHeuristics(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+	writeFile(t, filepath.Join(tmpDir, "main.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "blob.c"), "\x00\x01\x02"+source)
+	writeFile(t, filepath.Join(tmpDir, "bundle.js"), strings.Repeat("function f(a){return a*2};", 100))
+	writeFile(t, filepath.Join(tmpDir, "api.pb.go"), "// Code generated by protoc-gen-go. DO NOT EDIT.\n"+source)
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if got := buf.String(); strings.Count(got, "file=") != 1 || !strings.Contains(got, ",main.c\n") {
+		t.Errorf("expected only main.c, got:\n%s", got)
+	}
+
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{IncludeGenerated: true}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if got := buf.String(); strings.Count(got, "file=") != 2 || !strings.Contains(got, ",api.pb.go\n") {
+		t.Errorf("expected main.c and api.pb.go, got:\n%s", got)
+	}
+
+	if _, err := GenerateWFPFromFile(filepath.Join(tmpDir, "blob.c")); err == nil || !strings.Contains(err.Error(), "binary") {
+		t.Errorf("expected a binary content error, got %v", err)
+	}
+}
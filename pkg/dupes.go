@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Default number of lines every copy of a duplicated block must span
+const DefaultMinDupeLines = 5
+
+// DupesOptions configures duplicate code detection
+type DupesOptions struct {
+	WFP      WFPOptions // Fingerprinting options
+	MinHits  int        // Shared hashes required to report a block (default: 1)
+	MinLines int        // Lines every copy must span (default: DefaultMinDupeLines)
+}
+
+// dupeSpan is a line range of a file
+type dupeSpan struct {
+	file, from, to int
+}
+
+// dupeRun is a run of hashes found at two locations with the same line offset: a block of
+// code and one of its copies
+type dupeRun struct {
+	a, b   dupeSpan
+	hashes int
+}
+
+// dupeOffset identifies the hashes two files share at a given line offset
+type dupeOffset struct {
+	a, b, delta int
+}
+
+// FindDupes fingerprints a tree and reports the blocks of code it holds several copies of,
+// within a file or across files, without querying the knowledge base
+func FindDupes(ctx context.Context, root string, opts DupesOptions) (*models.DupesReport, error) {
+	entries, err := fingerprintTree(ctx, root, opts.WFP)
+	if err != nil {
+		return nil, err
+	}
+	c := newLocalCorpus()
+	c.add(root, entries)
+	return c.dupes(opts), nil
+}
+
+// dupes reports the duplicated blocks of the corpus. Every pair of occurrences of a hash is
+// keyed by the two files and their line offset; hashes of a same key on nearby lines form a
+// run, a block and its copy. Runs overlapping at a location are copies of the same block and
+// form one group.
+func (c *localCorpus) dupes(opts DupesOptions) *models.DupesReport {
+	minHits := opts.MinHits
+	if minHits < 1 {
+		minHits = 1
+	}
+	minLines := opts.MinLines
+	if minLines < 1 {
+		minLines = DefaultMinDupeLines
+	}
+
+	lines := make(map[dupeOffset][]int)
+	for _, hash := range c.sortedHashes() {
+		postings := c.index[hash]
+		for i, p := range postings {
+			for _, q := range postings[i+1:] {
+				if q.file < p.file || (q.file == p.file && q.line < p.line) {
+					p, q = q, p
+				}
+				if p.file == q.file && p.line == q.line {
+					continue
+				}
+				key := dupeOffset{a: p.file, b: q.file, delta: int(q.line) - int(p.line)}
+				lines[key] = append(lines[key], int(p.line))
+			}
+		}
+	}
+	keys := make([]dupeOffset, 0, len(lines))
+	for key := range lines {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		if ki.a != kj.a {
+			return ki.a < kj.a
+		}
+		if ki.b != kj.b {
+			return ki.b < kj.b
+		}
+		return ki.delta < kj.delta
+	})
+
+	var runs []dupeRun
+	for _, key := range keys {
+		found := lines[key]
+		sort.Ints(found)
+		for start := 0; start < len(found); {
+			end := start + 1
+			for end < len(found) && found[end]-found[end-1] <= RangeMergeTolerance+1 {
+				end++
+			}
+			from, to := found[start], found[end-1]
+			hashes := end - start
+			start = end
+			// Hashes on single lines are common idioms rather than copied code, and a block
+			// overlapping its own copy is a repetition of the same lines
+			if to == from || hashes < minHits || (key.a == key.b && to >= from+key.delta) {
+				continue
+			}
+			runs = append(runs, dupeRun{
+				a:      dupeSpan{file: key.a, from: from, to: to},
+				b:      dupeSpan{file: key.b, from: from + key.delta, to: to + key.delta},
+				hashes: hashes,
+			})
+		}
+	}
+
+	report := &models.DupesReport{Files: len(c.files), Groups: []models.DupeGroup{}}
+	for _, component := range groupRuns(runs) {
+		group := models.DupeGroup{}
+		for _, run := range component {
+			if run.hashes > group.Hashes {
+				group.Hashes = run.hashes
+			}
+		}
+		for i, span := range mergeSpans(component) {
+			lines := span.to - span.from + 1
+			group.Locations = append(group.Locations, models.DupeLocation{
+				File:  c.files[span.file].path,
+				Lines: fmt.Sprintf("%d-%d", span.from, span.to),
+				Span:  lines,
+			})
+			if i == 0 || lines < group.Lines {
+				group.Lines = lines
+			}
+		}
+		if group.Lines < minLines {
+			continue
+		}
+		report.Groups = append(report.Groups, group)
+	}
+
+	// Largest blocks first
+	sort.SliceStable(report.Groups, func(i, j int) bool {
+		gi, gj := report.Groups[i], report.Groups[j]
+		if gi.Lines != gj.Lines {
+			return gi.Lines > gj.Lines
+		}
+		return len(gi.Locations) > len(gj.Locations)
+	})
+	for i := range report.Groups {
+		report.Groups[i].ID = i + 1
+	}
+	return report
+}
+
+// groupRuns groups the runs linked by their locations: runs whose spans overlap in a file
+// involve the same copy of a block
+func groupRuns(runs []dupeRun) [][]dupeRun {
+	// Spans 2i and 2i+1 are the locations of run i
+	spans := make([]dupeSpan, 0, 2*len(runs))
+	for _, run := range runs {
+		spans = append(spans, run.a, run.b)
+	}
+	parent := make([]int, len(spans))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) { parent[find(i)] = find(j) }
+
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return spanLess(spans[order[i]], spans[order[j]]) })
+	cur, reach := -1, 0
+	for _, i := range order {
+		if cur >= 0 && spans[i].file == spans[cur].file && spans[i].from <= reach {
+			union(i, cur)
+			reach = max(reach, spans[i].to)
+			continue
+		}
+		cur, reach = i, spans[i].to
+	}
+	for i := range runs {
+		union(2*i, 2*i+1)
+	}
+
+	var components [][]dupeRun
+	index := make(map[int]int)
+	for i, run := range runs {
+		root := find(2 * i)
+		n, ok := index[root]
+		if !ok {
+			n = len(components)
+			index[root] = n
+			components = append(components, nil)
+		}
+		components[n] = append(components[n], run)
+	}
+	return components
+}
+
+// mergeSpans returns the locations of a group of runs: their spans, merged when they overlap
+func mergeSpans(runs []dupeRun) []dupeSpan {
+	spans := make([]dupeSpan, 0, 2*len(runs))
+	for _, run := range runs {
+		spans = append(spans, run.a, run.b)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spanLess(spans[i], spans[j]) })
+	var merged []dupeSpan
+	for _, span := range spans {
+		if last := len(merged) - 1; last >= 0 && merged[last].file == span.file && span.from <= merged[last].to {
+			merged[last].to = max(merged[last].to, span.to)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// spanLess orders spans by file and first line
+func spanLess(a, b dupeSpan) bool {
+	if a.file != b.file {
+		return a.file < b.file
+	}
+	return a.from < b.from
+}
+
+// WriteDupesText writes a duplicate code report as plain text
+func WriteDupesText(w io.Writer, report *models.DupesReport) error {
+	var b strings.Builder
+	duplicated := 0
+	for _, group := range report.Groups {
+		fmt.Fprintf(&b, "Group %d: %d copies of %d lines (%d shared hashes)\n", group.ID, len(group.Locations), group.Lines, group.Hashes)
+		for _, location := range group.Locations {
+			fmt.Fprintf(&b, "  %s:%s\n", location.File, location.Lines)
+		}
+		b.WriteString("\n")
+		duplicated += group.Lines * (len(group.Locations) - 1)
+	}
+	fmt.Fprintf(&b, "%d duplicated blocks in %d files, about %d duplicated lines\n", len(report.Groups), report.Files, duplicated)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
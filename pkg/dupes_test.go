@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDupes(t *testing.T) {
+	LoadFilters("")
+	dir := t.TempDir()
+	helpers := sampleCode("helpers", 6)
+	writeFile(t, filepath.Join(dir, "a.c"), messageTable("first", 20)+helpers)
+	writeFile(t, filepath.Join(dir, "lib", "b.c"), helpers+messageTable("second", 20))
+	// Copied twice within the same file
+	copied := sampleCode("copied", 5)
+	writeFile(t, filepath.Join(dir, "c.c"), copied+messageTable("third", 20)+copied)
+
+	report, err := FindDupes(context.Background(), dir, DupesOptions{MinHits: 3})
+	if err != nil {
+		t.Fatalf("FindDupes() error = %v", err)
+	}
+	if report.Files != 3 || len(report.Groups) != 2 {
+		t.Fatalf("expected 2 groups in 3 files, got %+v", report)
+	}
+
+	helpersGroup, copiedGroup := report.Groups[0], report.Groups[1]
+	if helpersGroup.ID != 1 || len(helpersGroup.Locations) != 2 || helpersGroup.Locations[0].File != "a.c" || helpersGroup.Locations[1].File != "lib/b.c" {
+		t.Errorf("unexpected cross-file group: %+v", helpersGroup)
+	}
+	if helpersGroup.Lines < 20 || helpersGroup.Hashes < 3 {
+		t.Errorf("cross-file group spans %d lines with %d hashes", helpersGroup.Lines, helpersGroup.Hashes)
+	}
+	if len(copiedGroup.Locations) != 2 || copiedGroup.Locations[0].File != "c.c" || copiedGroup.Locations[1].File != "c.c" {
+		t.Fatalf("unexpected same-file group: %+v", copiedGroup)
+	}
+	if copiedGroup.Locations[0].Lines == copiedGroup.Locations[1].Lines {
+		t.Errorf("copies within a file share the ranges %s", copiedGroup.Locations[0].Lines)
+	}
+
+	// Blocks shorter than the minimum span are not reported
+	report, _ = FindDupes(context.Background(), dir, DupesOptions{MinHits: 3, MinLines: 1000})
+	if len(report.Groups) != 0 {
+		t.Errorf("expected no group of 1000 lines, got %+v", report.Groups)
+	}
+}
+
+func TestFindDupes_SameFileBlocks(t *testing.T) {
+	LoadFilters("")
+	dir := t.TempDir()
+	// Blocks X and Y are both copied within a.c, in the order X Y Y X
+	x, y := messageTable("alpha", 14), messageTable("beta", 16)
+	writeFile(t, filepath.Join(dir, "a.c"), x+"\n"+y+"\n"+y+"\n"+x)
+
+	report, err := FindDupes(context.Background(), dir, DupesOptions{MinHits: 3})
+	if err != nil {
+		t.Fatalf("FindDupes() error = %v", err)
+	}
+	if len(report.Groups) != 2 {
+		t.Fatalf("expected a group for each block, got %+v", report.Groups)
+	}
+	// X is at lines 1-14 and 50-63, Y at lines 16-31 and 33-48: copies of X are 49 lines apart,
+	// copies of Y 17 lines apart
+	for _, group := range report.Groups {
+		if len(group.Locations) != 2 {
+			t.Fatalf("unexpected group: %+v", group)
+		}
+		var first, second int
+		fmt.Sscanf(group.Locations[0].Lines, "%d-", &first)
+		fmt.Sscanf(group.Locations[1].Lines, "%d-", &second)
+		x := first <= 14 && second-first == 49
+		y := first >= 16 && first <= 31 && second-first == 17
+		if !x && !y {
+			t.Errorf("group pairs %s with %s", group.Locations[0].Lines, group.Locations[1].Lines)
+		}
+	}
+}
+
+func TestWriteDupesText(t *testing.T) {
+	LoadFilters("")
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.c"), sampleCode("shared", 6))
+	writeFile(t, filepath.Join(dir, "b.c"), sampleCode("shared", 6))
+
+	report, err := FindDupes(context.Background(), dir, DupesOptions{})
+	if err != nil {
+		t.Fatalf("FindDupes() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteDupesText(&buf, report); err != nil {
+		t.Fatalf("WriteDupesText() error = %v", err)
+	}
+	for _, want := range []string{"Group 1: 2 copies of", "  a.c:", "  b.c:", "1 duplicated blocks in 2 files"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text report lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings recognized before fingerprinting
+const (
+	encodingUTF8    = "UTF-8"
+	encodingUTF8BOM = "UTF-8 with BOM"
+	encodingUTF16LE = "UTF-16LE"
+	encodingUTF16BE = "UTF-16BE"
+	encodingLatin1  = "Latin-1"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// decodeText detects the encoding of file contents and returns them as UTF-8 without BOM.
+// UTF-16 is recognized by its BOM or, without BOM, by the NUL bytes of ASCII characters.
+// Contents that are not valid UTF-8 are read as Latin-1.
+func decodeText(f []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(f, bomUTF8):
+		return f[len(bomUTF8):], encodingUTF8BOM
+	case bytes.HasPrefix(f, bomUTF16LE):
+		return decodeUTF16(f[2:], binary.LittleEndian), encodingUTF16LE
+	case bytes.HasPrefix(f, bomUTF16BE):
+		return decodeUTF16(f[2:], binary.BigEndian), encodingUTF16BE
+	}
+
+	if enc := sniffUTF16(f); enc == encodingUTF16LE {
+		return decodeUTF16(f, binary.LittleEndian), enc
+	} else if enc == encodingUTF16BE {
+		return decodeUTF16(f, binary.BigEndian), enc
+	}
+
+	if utf8.Valid(f) {
+		return f, encodingUTF8
+	}
+	text := make([]byte, 0, len(f)+len(f)/4)
+	for _, b := range f {
+		if b < utf8.RuneSelf {
+			text = append(text, b)
+		} else {
+			text = utf8.AppendRune(text, rune(b))
+		}
+	}
+	return text, encodingLatin1
+}
+
+// sniffUTF16 detects BOM-less UTF-16: mostly ASCII text leaves a NUL byte in every code unit,
+// always on the same side
+func sniffUTF16(f []byte) string {
+	sample := f
+	if len(sample) > contentSniffSize {
+		sample = sample[:contentSniffSize]
+	}
+	units := len(sample) / 2
+	if units < 2 {
+		return ""
+	}
+
+	evenZeros, oddZeros := 0, 0
+	for i := 0; i+1 < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	switch {
+	case oddZeros*10 >= units*7 && evenZeros*20 <= units:
+		return encodingUTF16LE
+	case evenZeros*10 >= units*7 && oddZeros*20 <= units:
+		return encodingUTF16BE
+	}
+	return ""
+}
+
+// decodeUTF16 converts UTF-16 code units to UTF-8, ignoring a trailing odd byte
+func decodeUTF16(f []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(f)/2)
+	for i := range units {
+		units[i] = order.Uint16(f[2*i:])
+	}
+	text := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		text = utf8.AppendRune(text, r)
+	}
+	return text
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes text as UTF-16 with the given byte order, optionally with a BOM
+func encodeUTF16(text string, order binary.ByteOrder, bom bool) []byte {
+	var out []byte
+	units := utf16.Encode([]rune(text))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	for _, u := range units {
+		unit := make([]byte, 2)
+		order.PutUint16(unit, u)
+		out = append(out, unit...)
+	}
+	return out
+}
+
+// latin1 encodes text with runes below 256 as Latin-1
+func latin1(text string) []byte {
+	var out []byte
+	for _, r := range text {
+		out = append(out, byte(r))
+	}
+	return out
+}
+
+func TestDecodeText(t *testing.T) {
+	text := "/* Résumé: Ünïcode İstanbul ½ */\nint main() { return 0; }\n"
+	latinText := "/* Résumé: Ünïcode ½ */\nint main() { return 0; }\n"
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     string
+		encoding string
+	}{
+		{"utf-8", []byte(text), text, encodingUTF8},
+		{"utf-8 bom", append([]byte{0xef, 0xbb, 0xbf}, text...), text, encodingUTF8BOM},
+		{"utf-16le bom", encodeUTF16(text, binary.LittleEndian, true), text, encodingUTF16LE},
+		{"utf-16be bom", encodeUTF16(text, binary.BigEndian, true), text, encodingUTF16BE},
+		{"utf-16le", encodeUTF16(text, binary.LittleEndian, false), text, encodingUTF16LE},
+		{"utf-16be", encodeUTF16(text, binary.BigEndian, false), text, encodingUTF16BE},
+		{"latin-1", latin1(latinText), latinText, encodingLatin1},
+	}
+	for _, tt := range tests {
+		got, encoding := decodeText(tt.data)
+		if string(got) != tt.want || encoding != tt.encoding {
+			t.Errorf("%s: decodeText() = %q (%s), want %q (%s)", tt.name, got, encoding, tt.want, tt.encoding)
+		}
+	}
+}
+
+func TestFingerprintData_Encodings(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 15; i++ {
+		b.WriteString("// Paramètre İ½ numéro ½ pour le calcul\nint compute(int value) { return value * 2; }\n")
+	}
+	text := b.String()
+
+	// Hash lines after the file= and fh2= headers
+	hashes := func(wfp string) string {
+		var lines []string
+		for _, line := range strings.Split(wfp, "\n") {
+			if !strings.HasPrefix(line, "file=") && !strings.HasPrefix(line, "fh2=") {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	want := hashes(fingerprintData("a.c", []byte(text), WFPOptions{HPSM: true}))
+	for name, data := range map[string][]byte{
+		"utf-8 bom":    append([]byte{0xef, 0xbb, 0xbf}, text...),
+		"utf-16le bom": encodeUTF16(text, binary.LittleEndian, true),
+		"utf-16be":     encodeUTF16(text, binary.BigEndian, false),
+	} {
+		wfp := fingerprintData("a.c", data, WFPOptions{HPSM: true})
+		if got := hashes(wfp); got != want {
+			t.Errorf("%s: hashes differ from the UTF-8 copy:\n%s\n---\n%s", name, got, want)
+		}
+		// The file= header still identifies the original bytes
+		if !strings.HasPrefix(wfp, "file="+md5Hex(data)+",") {
+			t.Errorf("%s: file= MD5 should be computed over the original bytes", name)
+		}
+	}
+
+	decoded, _ := decodeText(encodeUTF16(text, binary.LittleEndian, false))
+	if reason := contentSkipReason(decoded, WFPOptions{}); reason != "" {
+		t.Errorf("UTF-16 source should not be skipped, got %q", reason)
+	}
+}
+
+func TestLineEndingsHash_UTF16(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    binary.ByteOrder
+		encoding string
+		bom      bool
+	}{
+		{"le bom", binary.LittleEndian, encodingUTF16LE, true},
+		{"le", binary.LittleEndian, encodingUTF16LE, false},
+		{"be bom", binary.BigEndian, encodingUTF16BE, true},
+	}
+	for _, tt := range tests {
+		lf := encodeUTF16("int a;\nint \u0a0d;\n", tt.order, tt.bom)
+		crlf := encodeUTF16("int a;\r\nint \u0a0d;\r\n", tt.order, tt.bom)
+
+		if got := lineEndingsHash(lf, tt.encoding); got != md5Hex(crlf) {
+			t.Errorf("%s: fh2 of an LF file should be the MD5 of its CRLF version", tt.name)
+		}
+		if got := lineEndingsHash(crlf, tt.encoding); got != md5Hex(lf) {
+			t.Errorf("%s: fh2 of a CRLF file should be the MD5 of its LF version", tt.name)
+		}
+		// U+0A0D holds the bytes of CR and LF, but no line ending
+		if got := lineEndingsHash(encodeUTF16("int \u0a0d;", tt.order, tt.bom), tt.encoding); got != "" {
+			t.Errorf("%s: files without line endings should have no fh2, got %s", tt.name, got)
+		}
+	}
+
+	wfp := fingerprintData("a.c", encodeUTF16("int a;\nint b;\n", binary.LittleEndian, true), WFPOptions{})
+	if !strings.Contains(wfp, "\nfh2="+md5Hex(encodeUTF16("int a;\r\nint b;\r\n", binary.LittleEndian, true))+"\n") {
+		t.Errorf("expected the fh2 of the UTF-16 file:\n%s", wfp)
+	}
+}
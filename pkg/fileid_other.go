@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+//go:build !unix
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileIDOf identifies a file by its resolved absolute path, as device and inode are not available
+func fileIDOf(path string, info os.FileInfo) (fileID, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileID{}, false
+	}
+	if abs, err := filepath.Abs(resolved); err == nil {
+		resolved = abs
+	}
+	return fileID{path: resolved}, true
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Default size in bytes at or below which files are not fingerprinted
+const DefaultMinFileSize = 100
+
+// Include/exclude patterns and size limits of the current file filters
+var fileFilters models.Filters
+
+// LoadFilters sets the file filters to the built-in extension lists and size limits,
+// extended with the "filters" section of a settings file if fileName is not empty
+func LoadFilters(fileName string) error {
+	bannedFiles = make(map[string]bool)
+	onlyMD5 = make(map[string]bool)
+	for r := range SKIP_SNIPPET_EXT {
+		onlyMD5[SKIP_SNIPPET_EXT[r]] = true
+	}
+	for r := range FILTERED_EXT {
+		bannedFiles[FILTERED_EXT[r]] = true
+	}
+	minFileSize := int64(DefaultMinFileSize)
+	fileFilters = models.Filters{MinFileSize: &minFileSize}
+
+	if fileName == "" {
+		return nil
+	}
+	settings, err := readSettings(fileName)
+	if err != nil {
+		return err
+	}
+	if settings.Filters != nil {
+		if err := ApplyFilters(*settings.Filters); err != nil {
+			return fmt.Errorf("invalid filters in %s: %v", fileName, err)
+		}
+		DebugLog("Loaded file filters from %s\n", fileName)
+	}
+	return nil
+}
+
+// ApplyFilters extends the current file filters: patterns and extensions are added,
+// a set minimum size and a non-zero maximum size replace the current ones
+func ApplyFilters(f models.Filters) error {
+	if bannedFiles == nil {
+		LoadFilters("")
+	}
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return fmt.Errorf("bad pattern %q: %v", pattern, err)
+		}
+	}
+	if (f.MinFileSize != nil && *f.MinFileSize < 0) || f.MaxFileSize < 0 {
+		return fmt.Errorf("file size limits must not be negative")
+	}
+
+	if f.OverrideDefaults {
+		bannedFiles = make(map[string]bool)
+		onlyMD5 = make(map[string]bool)
+	}
+	for _, ext := range f.SkipExtensions {
+		if strings.TrimSpace(ext) == "" {
+			continue
+		}
+		bannedFiles[normalizeExtension(ext)] = true
+	}
+	for _, ext := range f.AllowExtensions {
+		ext = normalizeExtension(ext)
+		delete(bannedFiles, ext)
+		delete(onlyMD5, ext)
+	}
+
+	fileFilters.Include = append(fileFilters.Include, f.Include...)
+	fileFilters.Exclude = append(fileFilters.Exclude, f.Exclude...)
+	if f.MinFileSize != nil {
+		minFileSize := *f.MinFileSize
+		fileFilters.MinFileSize = &minFileSize
+	}
+	if f.MaxFileSize > 0 {
+		fileFilters.MaxFileSize = f.MaxFileSize
+	}
+	if fileFilters.MaxFileSize > 0 && fileFilters.MaxFileSize <= *fileFilters.MinFileSize {
+		return fmt.Errorf("maximum file size %d must be greater than the minimum %d", fileFilters.MaxFileSize, *fileFilters.MinFileSize)
+	}
+	return nil
+}
+
+// normalizeExtension accepts extensions with or without the leading dot ("go", ".go", "*.go")
+func normalizeExtension(ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), "*")
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// excludedPath returns the exclude pattern matching a path relative to the walk root, or ""
+func excludedPath(relPath string) string {
+	for _, pattern := range fileFilters.Exclude {
+		if globMatch(pattern, relPath) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// fileSkipReason returns why a file is not fingerprinted, or "" when it passes the filters
+func fileSkipReason(relPath string, size int64) string {
+	if pattern := excludedPath(relPath); pattern != "" {
+		return fmt.Sprintf("excluded by %s", pattern)
+	}
+	if len(fileFilters.Include) > 0 {
+		included := false
+		for _, pattern := range fileFilters.Include {
+			if globMatch(pattern, relPath) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return "not matched by any include pattern"
+		}
+	}
+
+	if minFileSize := *fileFilters.MinFileSize; minFileSize > 0 && size <= minFileSize {
+		return fmt.Sprintf("file too small (must be > %d bytes)", minFileSize)
+	}
+	if fileFilters.MaxFileSize > 0 && size > fileFilters.MaxFileSize {
+		return fmt.Sprintf("file too large (must be <= %d bytes)", fileFilters.MaxFileSize)
+	}
+
+	ext := path.Ext(relPath)
+	if bannedFiles[ext] {
+		return fmt.Sprintf("file extension %s is in skip list", ext)
+	}
+	if onlyMD5[ext] {
+		return fmt.Sprintf("file extension %s is banned", ext)
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestLoadFilters_Settings(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	settingsFile := filepath.Join(t.TempDir(), "plagicheck.json")
+	writeFile(t, settingsFile, `{
+  "filters": {
+    "exclude": ["test/**"],
+    "skip_extensions": ["go", ".proto"],
+    "allow_extensions": [".json"],
+    "min_file_size": 50,
+    "max_file_size": 1000
+  }
+}`)
+	if err := LoadFilters(settingsFile); err != nil {
+		t.Fatalf("LoadFilters failed: %v", err)
+	}
+
+	if !bannedFiles[".go"] || !bannedFiles[".proto"] {
+		t.Error("skip_extensions should be added to the skip list")
+	}
+	if bannedFiles[".json"] {
+		t.Error("allow_extensions should be removed from the skip list")
+	}
+	if !bannedFiles[".yaml"] {
+		t.Error("built-in extensions should be kept")
+	}
+	if *fileFilters.MinFileSize != 50 || fileFilters.MaxFileSize != 1000 {
+		t.Errorf("unexpected size limits %d-%d", *fileFilters.MinFileSize, fileFilters.MaxFileSize)
+	}
+
+	// Reloading without a file restores the defaults
+	if err := LoadFilters(""); err != nil {
+		t.Fatalf("LoadFilters failed: %v", err)
+	}
+	if bannedFiles[".go"] || len(fileFilters.Exclude) != 0 || *fileFilters.MinFileSize != DefaultMinFileSize {
+		t.Error("LoadFilters(\"\") should restore the built-in filters")
+	}
+}
+
+func TestApplyFilters_Invalid(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	tests := []models.Filters{
+		{Include: []string{"[abc"}},
+		{MinFileSize: size(-1)},
+		{MinFileSize: size(500), MaxFileSize: 200},
+	}
+	for _, f := range tests {
+		LoadFilters("")
+		if err := ApplyFilters(f); err == nil {
+			t.Errorf("ApplyFilters(%+v) should fail", f)
+		}
+	}
+}
+
+func TestApplyFilters_OverrideDefaults(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	LoadFilters("")
+	if err := ApplyFilters(models.Filters{OverrideDefaults: true, SkipExtensions: []string{"*.txt"}}); err != nil {
+		t.Fatalf("ApplyFilters failed: %v", err)
+	}
+	if len(bannedFiles) != 1 || !bannedFiles[".txt"] || len(onlyMD5) != 0 {
+		t.Errorf("expected only .txt to be skipped, got %v and %v", bannedFiles, onlyMD5)
+	}
+}
+
+func TestFileSkipReason(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	LoadFilters("")
+	err := ApplyFilters(models.Filters{
+		Include:     []string{"*.c", "*.go"},
+		Exclude:     []string{"test/**", "*_gen.go"},
+		MaxFileSize: 10000,
+	})
+	if err != nil {
+		t.Fatalf("ApplyFilters failed: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		size   int64
+		reason string
+	}{
+		{"src/main.c", 500, ""},
+		{"main.go", 500, ""},
+		{"test/fixture.c", 500, "excluded by test/**"},
+		{"pkg/types_gen.go", 500, "excluded by *_gen.go"},
+		{"script.py", 500, "not matched by any include pattern"},
+		{"tiny.c", 100, "file too small"},
+		{"huge.c", 20000, "file too large"},
+	}
+	for _, tt := range tests {
+		reason := fileSkipReason(tt.path, tt.size)
+		if tt.reason == "" && reason != "" {
+			t.Errorf("fileSkipReason(%q) = %q, want no reason", tt.path, reason)
+		} else if !strings.HasPrefix(reason, tt.reason) {
+			t.Errorf("fileSkipReason(%q) = %q, want %q", tt.path, reason, tt.reason)
+		}
+	}
+}
+
+func TestApplyFilters_NoMinFileSize(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	settingsFile := filepath.Join(t.TempDir(), "plagicheck.json")
+	writeFile(t, settingsFile, `{"filters": {"min_file_size": 0}}`)
+	if err := LoadFilters(settingsFile); err != nil {
+		t.Fatalf("LoadFilters failed: %v", err)
+	}
+	// Filters without a minimum size (command line defaults) keep the one of the settings
+	if err := ApplyFilters(models.Filters{Exclude: []string{"test/**"}}); err != nil {
+		t.Fatalf("ApplyFilters failed: %v", err)
+	}
+	if reason := fileSkipReason("tiny.c", 10); reason != "" {
+		t.Errorf("min_file_size 0 should keep small files, got %q", reason)
+	}
+
+	LoadFilters("")
+	if err := ApplyFilters(models.Filters{MinFileSize: size(0)}); err != nil {
+		t.Fatalf("ApplyFilters failed: %v", err)
+	}
+	if reason := fileSkipReason("tiny.c", 10); reason != "" {
+		t.Errorf("--min-file-size 0 should keep small files, got %q", reason)
+	}
+}
+
+// size returns a pointer to a file size limit
+func size(n int64) *int64 {
+	return &n
+}
+
+func TestGenerateWFP_Filters(t *testing.T) {
+	t.Cleanup(func() { LoadFilters("") })
+
+	tmpDir := t.TempDir()
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+	writeFile(t, filepath.Join(tmpDir, "main.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "tool.py"), source)
+	writeFile(t, filepath.Join(tmpDir, "test", "fixture.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "src", "big.c"), strings.Repeat(source, 10))
+	writeFile(t, filepath.Join(tmpDir, "src", "small.c"), source[:200])
+
+	LoadFilters("")
+	err := ApplyFilters(models.Filters{Exclude: []string{"test/**"}, SkipExtensions: []string{"py"}, MinFileSize: size(300), MaxFileSize: 1000})
+	if err != nil {
+		t.Fatalf("ApplyFilters failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 2}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "main.c") {
+		t.Error("expected main.c in WFP")
+	}
+	for _, name := range []string{"tool.py", "fixture.c", "big.c", "small.c"} {
+		if strings.Contains(out, name) {
+			t.Errorf("expected %s to be filtered", name)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// gitChange is a file added or modified between two revisions
+type gitChange struct {
+	Path  string         // Relative to the scan root, with forward slashes
+	Lines []models.Range // Changed line hunks of the new revision (From and To inclusive)
+}
+
+// Revision the changed files are read from, empty when not scanning a git diff
+var gitHead string
+
+// Changed line hunks of the files of a git diff scan, by file path
+var changedLines map[string][]models.Range
+
+var (
+	hunkPattern    = regexp.MustCompile(`^@@ -[0-9]+(?:,[0-9]+)? \+([0-9]+)(?:,([0-9]+))? @@`)
+	hashLinePrefix = regexp.MustCompile(`^([0-9]+)=`)
+)
+
+// parseGitRange splits a <base>..<head> (or <base>...<head>) revision range.
+// An empty head stands for HEAD, as in git.
+func parseGitRange(spec string) (base, head string, err error) {
+	sep := ".."
+	if strings.Contains(spec, "...") {
+		sep = "..."
+	}
+	parts := strings.SplitN(spec, sep, 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", fmt.Errorf("invalid git revision range %q (expected <base>..<head>)", spec)
+	}
+	// Revisions are passed to git as arguments, they must not be read as options
+	if strings.HasPrefix(parts[0], "-") || strings.HasPrefix(parts[1], "-") {
+		return "", "", fmt.Errorf("invalid git revision range %q (revisions cannot start with '-')", spec)
+	}
+	head = parts[1]
+	if head == "" {
+		head = "HEAD"
+	}
+	return parts[0], head, nil
+}
+
+// runGit runs a git command in dir and returns its standard output
+func runGit(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		// Name the subcommand, after the -c configuration options
+		name := ""
+		for i := 0; i < len(args) && name == ""; i++ {
+			if args[i] == "-c" {
+				i++
+				continue
+			}
+			name = args[i]
+		}
+		return nil, fmt.Errorf("git %s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// readGitFile returns the contents of a file under root as of the head revision of a git diff scan
+func readGitFile(root, relPath string) ([]byte, error) {
+	return runGit(root, "show", gitHead+":./"+relPath)
+}
+
+// gitDiffChanges lists the files under root added or modified in a revision range,
+// with the line hunks they changed. Deleted files and pure renames are left out.
+func gitDiffChanges(root, spec string) ([]gitChange, error) {
+	if _, _, err := parseGitRange(spec); err != nil {
+		return nil, err
+	}
+	output, err := runGit(root, "-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/", "-U0", "-M", "--relative", "--diff-filter=ACMR", spec, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []gitChange
+	var current *gitChange
+	// File headers come between a "diff" line and the first hunk: with -U0, an added line
+	// starting with "++ " also starts with "+++ "
+	inHeader := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			current = nil
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if !strings.HasPrefix(name, "b/") {
+				current = nil
+				continue
+			}
+			changes = append(changes, gitChange{Path: strings.TrimPrefix(name, "b/")})
+			current = &changes[len(changes)-1]
+		case strings.HasPrefix(line, "@@ "):
+			inHeader = false
+			if current == nil {
+				continue
+			}
+			m := hunkPattern.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			from, _ := strconv.Atoi(m[1])
+			count := 1
+			if m[2] != "" {
+				count, _ = strconv.Atoi(m[2])
+			}
+			// Hunks that only delete lines add nothing to the new revision
+			if count > 0 {
+				current.Lines = append(current.Lines, models.Range{From: from, To: from + count - 1})
+			}
+		}
+	}
+	return changes, scanner.Err()
+}
+
+// listGitDiff selects the files changed in opts.GitDiff for fingerprinting.
+// The files are read from the head revision by the fingerprinting workers.
+func listGitDiff(root string, opts WFPOptions) error {
+	_, head, err := parseGitRange(opts.GitDiff)
+	if err != nil {
+		return err
+	}
+	changes, err := gitDiffChanges(root, opts.GitDiff)
+	if err != nil {
+		return err
+	}
+	gitHead = head
+
+	loadedDirs := make(map[string]bool)
+	for _, change := range changes {
+		filePath := filepath.Join(root, filepath.FromSlash(change.Path))
+		name := filepath.Base(filePath)
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(change.Path, ".") || strings.Contains(change.Path, "/.") {
+			DebugLog("Skipping %s: hidden file\n", filePath)
+			continue
+		}
+		if gitDiffIgnored(root, change.Path, loadedDirs) {
+			continue
+		}
+		if pattern := excludedPath(change.Path); pattern != "" {
+			DebugLog("Skipping %s: excluded by %s\n", filePath, pattern)
+			continue
+		}
+		if isMinified(name) {
+			DebugLog("Skipping minimized file: %s\n", filePath)
+			continue
+		}
+		wfps = append(wfps, filePath)
+		changedLines[filePath] = change.Lines
+	}
+	DebugLog("Git diff %s changes %d files, %d selected\n", opts.GitDiff, len(changes), len(wfps))
+
+	// Declared dependencies come from the manifests of the whole tree, not only the changed ones,
+	// read from the head revision as the changed files
+	if output, err := runGit(root, "-c", "core.quotePath=false", "ls-tree", "-r", "--name-only", head); err == nil {
+		for _, name := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if isManifest(filepath.Base(name)) {
+				manifests = append(manifests, filepath.Join(root, filepath.FromSlash(name)))
+			}
+		}
+	}
+	return nil
+}
+
+// gitDiffIgnored reports whether a changed file or one of its directories is excluded by the
+// ignore files, which are loaded as of the head revision from the root down as the
+// directories are first seen
+func gitDiffIgnored(root, relPath string, loadedDirs map[string]bool) bool {
+	if ignores == nil {
+		return false
+	}
+	segments := strings.Split(relPath, "/")
+	for i := 0; i < len(segments); i++ {
+		dir := strings.Join(segments[:i], "/")
+		if dir != "" && ignores.ignored(dir, true) {
+			return true
+		}
+		if !loadedDirs[dir] {
+			loadedDirs[dir] = true
+			ignores.load(dir, func(name string) ([]byte, error) {
+				return readGitFile(root, path.Join(dir, name))
+			})
+		}
+	}
+	return ignores.ignored(relPath, false)
+}
+
+// fingerprintGitFile generates the WFP of a changed file as of the head revision
+func fingerprintGitFile(root, filePath string, opts WFPOptions) string {
+	relPath, err := filepath.Rel(root, filePath)
+	if err != nil {
+		return ""
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	data, err := readGitFile(root, relPath)
+	if err != nil {
+		DebugLog("Skipping %s: %v\n", filePath, err)
+		return ""
+	}
+	text, encoding := decodeText(data)
+	reason := fileSkipReason(relPath, int64(len(data)))
+	if reason == "" {
+		reason = contentSkipReason(text, opts)
+	}
+	if reason != "" {
+		DebugLog("Skipping %s: %s\n", filePath, reason)
+		return ""
+	}
+
+	wfp := fingerprintText(opts.wfpPath(root, filePath), data, text, encoding, opts)
+	if opts.ChangedLinesOnly {
+		wfp = keepChangedLines(wfp, changedLines[filePath])
+	}
+	return wfp
+}
+
+// keepChangedLines removes from a WFP the hashes of lines outside the changed hunks,
+// so that snippet matches only cover changed code
+func keepChangedLines(wfp string, hunks []models.Range) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(wfp, "\n") {
+		if m := hashLinePrefix.FindStringSubmatch(line); m != nil {
+			lineNum, _ := strconv.Atoi(m[1])
+			changed := false
+			for _, hunk := range hunks {
+				if lineNum >= hunk.From && lineNum <= hunk.To {
+					changed = true
+					break
+				}
+			}
+			if !changed {
+				continue
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// gitRepo creates a repository with two commits and returns its directory
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("int value_%d = compute(%d, input);", i, i))
+	}
+	git("init", "-q")
+	writeFile(t, filepath.Join(dir, "src", "stable.c"), strings.Join(lines, "\n")+"\n")
+	writeFile(t, filepath.Join(dir, "src", "edited.c"), strings.Join(lines, "\n")+"\n")
+	writeFile(t, filepath.Join(dir, "removed.c"), strings.Join(lines, "\n")+"\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+
+	lines[19] = "int changed_line = rewrite(20, other_input, 42);"
+	writeFile(t, filepath.Join(dir, "src", "edited.c"), strings.Join(lines, "\n")+"\n")
+	writeFile(t, filepath.Join(dir, "src", "added.c"), strings.Repeat("long twice(long v) { return v + v; }\n", 10))
+	git("rm", "-q", "removed.c")
+	git("add", "-A")
+	git("commit", "-q", "-m", "change")
+	return dir
+}
+
+func TestParseGitRange(t *testing.T) {
+	tests := []struct {
+		spec, base, head string
+		ok               bool
+	}{
+		{"main..feature", "main", "feature", true},
+		{"origin/main...HEAD", "origin/main", "HEAD", true},
+		{"v1.0..", "v1.0", "HEAD", true},
+		{"main", "", "", false},
+		{"..feature", "", "", false},
+		{"--output=/tmp/x..HEAD", "", "", false},
+		{"main..-p", "", "", false},
+	}
+	for _, tt := range tests {
+		base, head, err := parseGitRange(tt.spec)
+		if (err == nil) != tt.ok || base != tt.base || head != tt.head {
+			t.Errorf("parseGitRange(%q) = %q, %q, %v", tt.spec, base, head, err)
+		}
+	}
+}
+
+func TestGitDiffChanges(t *testing.T) {
+	dir := gitRepo(t)
+
+	changes, err := gitDiffChanges(dir, "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("gitDiffChanges failed: %v", err)
+	}
+	got := make(map[string][]models.Range)
+	for _, c := range changes {
+		got[c.Path] = c.Lines
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected added.c and edited.c, got %v", got)
+	}
+	if lines := got["src/edited.c"]; len(lines) != 1 || lines[0].From != 20 || lines[0].To != 20 {
+		t.Errorf("expected line 20 changed in edited.c, got %v", lines)
+	}
+	if lines := got["src/added.c"]; len(lines) != 1 || lines[0].From != 1 || lines[0].To != 10 {
+		t.Errorf("expected lines 1-10 added in added.c, got %v", lines)
+	}
+
+	// Paths are relative to the scanned subdirectory
+	changes, err = gitDiffChanges(filepath.Join(dir, "src"), "HEAD~1..HEAD")
+	if err != nil || len(changes) != 2 || !strings.HasSuffix(changes[0].Path, ".c") || strings.HasPrefix(changes[0].Path, "src/") {
+		t.Errorf("expected paths relative to src, got %v (%v)", changes, err)
+	}
+}
+
+func TestGenerateWFP_GitDiff(t *testing.T) {
+	dir := gitRepo(t)
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), dir, &buf, WFPOptions{GitDiff: "HEAD~1..HEAD"}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	out := buf.String()
+	if strings.Count(out, "file=") != 2 || !strings.Contains(out, ",src/edited.c\n") || !strings.Contains(out, ",src/added.c\n") {
+		t.Errorf("expected only the changed files, got:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), dir, &buf, WFPOptions{GitDiff: "HEAD~1..HEAD", ChangedLinesOnly: true}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	entries := strings.Split(buf.String(), "file=")
+	for _, entry := range entries {
+		if !strings.Contains(entry, "edited.c") {
+			continue
+		}
+		for _, line := range strings.Split(entry, "\n")[1:] {
+			if m := hashLinePrefix.FindStringSubmatch(line); m != nil && m[1] != "20" {
+				t.Errorf("unexpected hashes outside the changed hunk: %s", line)
+			}
+		}
+	}
+
+	// An empty diff produces an empty WFP
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), dir, &buf, WFPOptions{GitDiff: "HEAD..HEAD"}); err != nil {
+		t.Fatalf("GenerateWFP failed on an empty diff: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected an empty WFP, got:\n%s", buf.String())
+	}
+}
+
+func TestGenerateWFP_GitDiffManifests(t *testing.T) {
+	defer saveScanState().restore()
+	dir := gitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	writeFile(t, filepath.Join(dir, "package.json"), `{"dependencies": {"lodash": "4.17.21"}}`)
+	writeFile(t, filepath.Join(dir, "src", "added.c"), strings.Repeat("long thrice(long v) { return v + v + v; }\n", 10))
+	git("add", "-A")
+	git("commit", "-q", "-m", "lodash")
+	writeFile(t, filepath.Join(dir, "package.json"), `{"dependencies": {"express": "4.18.0"}}`)
+	git("add", "-A")
+	git("commit", "-q", "-m", "express")
+
+	// Manifests are read from the head of the range, not from the checked out revision
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), dir, &buf, WFPOptions{GitDiff: "HEAD~2..HEAD~1"}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if declaredDeps[dependencyKey("npm", "lodash")] == nil || declaredDeps[dependencyKey("npm", "express")] != nil {
+		t.Errorf("expected the dependencies of HEAD~1, got %v", declaredDeps)
+	}
+}
+
+func TestGitDiffChanges_PlusLines(t *testing.T) {
+	dir := gitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("int value_%d = compute(%d, input);", i, i))
+	}
+	// With -U0 the first hunk adds the diff line "+++ counter;"
+	lines[4] = "++ counter;"
+	lines[29] = "int other_line = rewrite(30, other_input, 7);"
+	writeFile(t, filepath.Join(dir, "src", "stable.c"), strings.Join(lines, "\n")+"\n")
+	git("commit", "-q", "-a", "-m", "increment")
+
+	changes, err := gitDiffChanges(dir, "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("gitDiffChanges failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Path != "src/stable.c" || len(changes[0].Lines) != 2 || changes[0].Lines[1].From != 30 {
+		t.Errorf("expected lines 5 and 30 changed in stable.c, got %+v", changes)
+	}
+}
+
+func TestGenerateWFP_GitDiffIgnoreFiles(t *testing.T) {
+	dir := gitRepo(t)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	writeFile(t, filepath.Join(dir, ".plagicheckignore"), "src/edited.c\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "ignore edited.c")
+	// Ignore files of the working tree are not those of the head revision
+	if err := os.Remove(filepath.Join(dir, ".plagicheckignore")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, ".gitignore"), "added.c\n")
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), dir, &buf, WFPOptions{GitDiff: "HEAD~2..HEAD"}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if out := buf.String(); strings.Count(out, "file=") != 1 || !strings.Contains(out, ",src/added.c\n") {
+		t.Errorf("expected only added.c, got:\n%s", out)
+	}
+}
+
+func TestRunGit_Error(t *testing.T) {
+	dir := gitRepo(t)
+	_, err := runGit(dir, "-c", "core.quotePath=false", "show", "no-such-revision:file")
+	if err == nil || !strings.HasPrefix(err.Error(), "git show:") {
+		t.Errorf("expected the error to name the subcommand, got %v", err)
+	}
+}
+
+func TestKeepChangedLines(t *testing.T) {
+	wfp := "file=abc,300,x.c\nfh2=def\n3=aaaa\n7=bbbb,cccc\n12=dddd\n"
+	got := keepChangedLines(wfp, []models.Range{{From: 5, To: 8}, {From: 12, To: 12}})
+	want := "file=abc,300,x.c\nfh2=def\n7=bbbb,cccc\n12=dddd\n"
+	if got != want {
+		t.Errorf("keepChangedLines() = %q, want %q", got, want)
+	}
+}
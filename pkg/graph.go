@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// pairSimilarity is the weight of a pair: the higher of its two similarities
+func pairSimilarity(pair models.SimilarPair) float64 {
+	return math.Max(pair.SimilarityA, pair.SimilarityB)
+}
+
+// clusterSubmissions groups the submissions linked by pairs at or above the cluster threshold
+// (connected components, i.e. single linkage). Clusters are numbered from 1, largest first.
+func clusterSubmissions(report *models.CohortReport) []models.Cluster {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(name string) string {
+		if parent[name] == "" || parent[name] == name {
+			return name
+		}
+		root := find(parent[name])
+		parent[name] = root
+		return root
+	}
+
+	maxSimilarity := make(map[string]float64)
+	for _, pair := range report.Pairs {
+		similarity := pairSimilarity(pair)
+		if similarity < report.ClusterThreshold {
+			continue
+		}
+		a, b := find(pair.A), find(pair.B)
+		if a != b {
+			if b < a {
+				a, b = b, a
+			}
+			parent[b] = a
+			maxSimilarity[a] = math.Max(maxSimilarity[a], maxSimilarity[b])
+		}
+		maxSimilarity[a] = math.Max(maxSimilarity[a], similarity)
+	}
+
+	members := make(map[string][]string)
+	for _, submission := range report.Submissions {
+		root := find(submission.Name)
+		members[root] = append(members[root], submission.Name)
+	}
+
+	clusters := []models.Cluster{}
+	for root, names := range members {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		clusters = append(clusters, models.Cluster{Submissions: names, MaxSimilarity: maxSimilarity[root]})
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Submissions) != len(clusters[j].Submissions) {
+			return len(clusters[i].Submissions) > len(clusters[j].Submissions)
+		}
+		return clusters[i].Submissions[0] < clusters[j].Submissions[0]
+	})
+	for i := range clusters {
+		clusters[i].ID = i + 1
+	}
+	return clusters
+}
+
+// SimilarityGraph returns the similarity graph of a cohort report: one node per submission
+// and one edge per similar pair, weighted by its similarity
+func SimilarityGraph(report *models.CohortReport) *models.SimilarityGraph {
+	cluster := make(map[string]int)
+	for _, c := range report.Clusters {
+		for _, name := range c.Submissions {
+			cluster[name] = c.ID
+		}
+	}
+
+	graph := &models.SimilarityGraph{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	for _, s := range report.Submissions {
+		graph.Nodes = append(graph.Nodes, models.GraphNode{ID: s.Name, Files: s.Files, Hashes: s.Hashes, Cluster: cluster[s.Name]})
+	}
+	for _, pair := range report.Pairs {
+		graph.Edges = append(graph.Edges, models.GraphEdge{
+			Source:       pair.A,
+			Target:       pair.B,
+			Weight:       pairSimilarity(pair),
+			SharedHashes: pair.Hashes,
+			SharedLinesA: pair.SharedLinesA,
+			SharedLinesB: pair.SharedLinesB,
+		})
+	}
+	return graph
+}
+
+// WriteGraphJSON writes the similarity graph of a cohort report as JSON
+func WriteGraphJSON(w io.Writer, report *models.CohortReport) error {
+	output, err := json.MarshalIndent(SimilarityGraph(report), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(output))
+	return err
+}
+
+// WriteGraphDOT writes the similarity graph of a cohort report in GraphViz DOT format.
+// Clusters are drawn as subgraphs; edges below the cluster threshold are dashed.
+func WriteGraphDOT(w io.Writer, report *models.CohortReport) error {
+	graph := SimilarityGraph(report)
+	var b strings.Builder
+	b.WriteString("graph similarity {\n")
+	b.WriteString("  node [shape=box];\n")
+
+	clustered := make(map[string]bool)
+	for _, c := range report.Clusters {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", c.ID)
+		fmt.Fprintf(&b, "    label=%s;\n", strconv.Quote(fmt.Sprintf("cluster %d (max %.2f%%)", c.ID, c.MaxSimilarity)))
+		for _, name := range c.Submissions {
+			fmt.Fprintf(&b, "    %s;\n", strconv.Quote(name))
+			clustered[name] = true
+		}
+		b.WriteString("  }\n")
+	}
+	for _, node := range graph.Nodes {
+		if !clustered[node.ID] {
+			fmt.Fprintf(&b, "  %s;\n", strconv.Quote(node.ID))
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		style := "solid"
+		if edge.Weight < report.ClusterThreshold {
+			style = "dashed"
+		}
+		fmt.Fprintf(&b, "  %s -- %s [weight=%d, label=%s, penwidth=%.2f, style=%s];\n",
+			strconv.Quote(edge.Source), strconv.Quote(edge.Target), int(math.Round(edge.Weight)),
+			strconv.Quote(fmt.Sprintf("%.2f%% (%d/%d lines)", edge.Weight, edge.SharedLinesA, edge.SharedLinesB)),
+			1+edge.Weight/25, style)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// ringReport returns a cohort with two rings (a-b-c and d-e) and a weak link between them
+func ringReport() *models.CohortReport {
+	report := &models.CohortReport{ClusterThreshold: 50}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		report.Submissions = append(report.Submissions, models.Submission{Name: name, Files: 1, Hashes: 100})
+	}
+	report.Pairs = []models.SimilarPair{
+		{A: "a", B: "b", SimilarityA: 90, SimilarityB: 80, Hashes: 90, SharedLinesA: 120, SharedLinesB: 110},
+		{A: "b", B: "c", SimilarityA: 40, SimilarityB: 60, Hashes: 60, SharedLinesA: 70, SharedLinesB: 75},
+		{A: "d", B: "e", SimilarityA: 55, SimilarityB: 55, Hashes: 55, SharedLinesA: 60, SharedLinesB: 61},
+		{A: "c", B: "d", SimilarityA: 10, SimilarityB: 12, Hashes: 12, SharedLinesA: 9, SharedLinesB: 10},
+	}
+	report.Clusters = clusterSubmissions(report)
+	return report
+}
+
+func TestClusterSubmissions(t *testing.T) {
+	report := ringReport()
+	want := []models.Cluster{
+		{ID: 1, Submissions: []string{"a", "b", "c"}, MaxSimilarity: 90},
+		{ID: 2, Submissions: []string{"d", "e"}, MaxSimilarity: 55},
+	}
+	if !reflect.DeepEqual(report.Clusters, want) {
+		t.Errorf("clusterSubmissions() = %+v, want %+v", report.Clusters, want)
+	}
+
+	report.ClusterThreshold = 10
+	if clusters := clusterSubmissions(report); len(clusters) != 1 || len(clusters[0].Submissions) != 5 {
+		t.Errorf("lower threshold: clusterSubmissions() = %+v, want one cluster of 5", clusters)
+	}
+}
+
+func TestWriteGraphJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphJSON(&buf, ringReport()); err != nil {
+		t.Fatalf("WriteGraphJSON() error = %v", err)
+	}
+	var graph models.SimilarityGraph
+	if err := json.Unmarshal(buf.Bytes(), &graph); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(graph.Nodes) != 6 || len(graph.Edges) != 4 {
+		t.Fatalf("got %d nodes and %d edges, want 6 and 4", len(graph.Nodes), len(graph.Edges))
+	}
+	if graph.Nodes[2].Cluster != 1 || graph.Nodes[4].Cluster != 2 || graph.Nodes[5].Cluster != 0 {
+		t.Errorf("unexpected node clusters: %+v", graph.Nodes)
+	}
+	if e := graph.Edges[1]; e.Weight != 60 || e.SharedLinesA != 70 || e.SharedLinesB != 75 || e.SharedHashes != 60 {
+		t.Errorf("unexpected edge: %+v", e)
+	}
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGraphDOT(&buf, ringReport()); err != nil {
+		t.Fatalf("WriteGraphDOT() error = %v", err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		"graph similarity {",
+		"subgraph cluster_1 {",
+		"subgraph cluster_2 {",
+		"  \"f\";\n",
+		`"a" -- "b" [weight=90, label="90.00% (120/110 lines)"`,
+		`"c" -- "d" [weight=12, label="12.00% (9/10 lines)", penwidth=1.48, style=dashed]`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output lacks %q:\n%s", want, dot)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Default name of the project settings file
+const SettingsFileName = "plagicheck.json"
+
+// Status assigned to matches covered by a declared component
+const StatusIdentified = "identified"
+
+var identifications []models.Identification
+
+// readSettings parses a project settings file
+func readSettings(fileName string) (*models.Settings, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings models.Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings file %s: %v", fileName, err)
+	}
+	return &settings, nil
+}
+
+// LoadIdentifications loads the declared components from a project settings file.
+// An empty fileName clears any previously loaded identifications.
+func LoadIdentifications(fileName string) error {
+	identifications = nil
+	if fileName == "" {
+		return nil
+	}
+
+	settings, err := readSettings(fileName)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range settings.Identified {
+		if id.URL == "" && id.Purl == "" && id.Path == "" && id.MD5 == "" {
+			return fmt.Errorf("identified entry %d has no url, purl, path or md5", i+1)
+		}
+		settings.Identified[i].MD5 = strings.ToLower(id.MD5)
+	}
+	identifications = settings.Identified
+	DebugLog("Loaded %d identified components from %s\n", len(identifications), fileName)
+	return nil
+}
+
+// matchIdentification reports whether a declared component covers a match of the given file
+func matchIdentification(id *models.Identification, entry *models.WFPData, match *models.MatchResult) bool {
+	if id.URL != "" && !wildcardMatch(id.URL, match.ReferenceURL) {
+		return false
+	}
+	if id.Purl != "" && !wildcardMatch(id.Purl, match.Purl) {
+		return false
+	}
+	if id.Path != "" && !globMatch(id.Path, entry.FilePath) {
+		return false
+	}
+	if id.MD5 != "" && id.MD5 != entry.MD5Hex && id.MD5 != match.ReferenceMD5 {
+		return false
+	}
+	return true
+}
+
+// applyIdentifications marks a match as identified when a declared component covers it
+func applyIdentifications(entry *models.WFPData, match *models.MatchResult) {
+	if match == nil || match.MatchType == "no_match" {
+		return
+	}
+	for i := range identifications {
+		if matchIdentification(&identifications[i], entry, match) {
+			DebugLog("Match of %s identified by declared component #%d\n", entry.FilePath, i+1)
+			match.Status = StatusIdentified
+			match.Identified = &identifications[i]
+			return
+		}
+	}
+}
+
+// FilterIdentified removes identified matches from the results.
+// Files left without any result are removed as well.
+func FilterIdentified(results map[string][]*models.MatchResult) {
+	for key, matches := range results {
+		var kept []*models.MatchResult
+		for _, m := range matches {
+			if m == nil || m.Status != StatusIdentified {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == 0 {
+			delete(results, key)
+		} else {
+			results[key] = kept
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.c", "src/lib/zlib.c", true},
+		{"src/*.c", "src/lib/zlib.c", false},
+		{"src/**/*.c", "src/lib/zlib.c", true},
+		{"src/**", "./src/lib/zlib.c", true},
+		{"third_party/**/zlib.c", "third_party/zlib.c", true},
+		{"vendor/**", "src/vendor.c", false},
+	}
+
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.name); got != tt.expected {
+			t.Errorf("globMatch(%q, %q) = %v, expected %v", tt.pattern, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestApplyIdentifications(t *testing.T) {
+	settings := `{
+  "identified": [
+    {"url": "https://github.com/madler/zlib/*", "reviewer": "alice", "note": "bundled zlib"},
+    {"path": "vendor/**", "md5": "00FFFFF25AFAA0D78FF1C6F41BA7F965"}
+  ]
+}`
+	tmpFile := filepath.Join(t.TempDir(), SettingsFileName)
+	if err := os.WriteFile(tmpFile, []byte(settings), 0644); err != nil {
+		t.Fatalf("failed to write settings: %v", err)
+	}
+	if err := LoadIdentifications(tmpFile); err != nil {
+		t.Fatalf("failed to load settings: %v", err)
+	}
+	defer LoadIdentifications("")
+
+	tests := []struct {
+		name     string
+		entry    models.WFPData
+		match    models.MatchResult
+		expected bool
+	}{
+		{
+			name:     "url pattern",
+			entry:    models.WFPData{FilePath: "src/inflate.c", MD5Hex: "aa"},
+			match:    models.MatchResult{MatchType: "code_snippet", ReferenceURL: "https://github.com/madler/zlib/archive/v1.3.zip"},
+			expected: true,
+		},
+		{
+			name:     "path and reference md5",
+			entry:    models.WFPData{FilePath: "vendor/x/y.c", MD5Hex: "aa"},
+			match:    models.MatchResult{MatchType: "code_snippet", ReferenceMD5: "00fffff25afaa0d78ff1c6f41ba7f965"},
+			expected: true,
+		},
+		{
+			name:     "path without md5",
+			entry:    models.WFPData{FilePath: "vendor/x/y.c", MD5Hex: "aa"},
+			match:    models.MatchResult{MatchType: "code_snippet", ReferenceMD5: "bb"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyIdentifications(&tt.entry, &tt.match)
+			if got := tt.match.Status == StatusIdentified; got != tt.expected {
+				t.Errorf("expected identified=%v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterIdentified(t *testing.T) {
+	results := map[string][]*models.MatchResult{
+		"a.c": {{MatchType: "full_file", Status: StatusIdentified}},
+		"b.c": {{MatchType: "code_snippet"}},
+	}
+
+	FilterIdentified(results)
+
+	if _, ok := results["a.c"]; ok {
+		t.Error("identified file should be removed")
+	}
+	if len(results["b.c"]) != 1 {
+		t.Error("unidentified match should be kept")
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Ignore files read in every directory of the walk, in increasing order of precedence
+var ignoreFileNames = []string{".gitignore", ".plagicheckignore"}
+
+// ignoreRule is a single pattern of an ignore file (gitignore syntax)
+type ignoreRule struct {
+	pattern string // Pattern without the negation prefix and the trailing slash
+	base    string // Directory holding the ignore file, relative to the walk root ("" for the root)
+	negate  bool   // Pattern starts with '!': re-includes matching paths
+	dirOnly bool   // Pattern ends with '/': only matches directories
+	source  string // Ignore file and line, for debug output
+}
+
+// ignoreMatcher holds the rules of every ignore file found during a directory walk
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+var ignores *ignoreMatcher
+
+// parseIgnoreLine converts a line of an ignore file into a rule.
+// It returns false for blank lines and comments.
+func parseIgnoreLine(line string) (ignoreRule, bool) {
+	var rule ignoreRule
+
+	// Trailing spaces are ignored unless escaped
+	line = strings.TrimRight(line, "\r")
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	line = strings.ReplaceAll(line, "\\ ", " ")
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// A leading "**/" matches in all directories, like a pattern without slashes
+	if strings.HasPrefix(line, "**/") && !strings.Contains(line[3:], "/") {
+		line = line[3:]
+	}
+	// A trailing "/**" matches everything inside a directory, but not the directory itself,
+	// so that negations can re-include files under it
+	if strings.HasSuffix(line, "/**") {
+		line += "/*"
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// loadDir reads the ignore files of a directory; relDir is its path relative to the walk root
+func (m *ignoreMatcher) loadDir(dir, relDir string) {
+	m.load(relDir, func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, name))
+	})
+}
+
+// load adds the rules of the ignore files of the directory relDir, read with readFile
+func (m *ignoreMatcher) load(relDir string, readFile func(name string) ([]byte, error)) {
+	for _, name := range ignoreFileNames {
+		data, err := readFile(name)
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			rule, ok := parseIgnoreLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			rule.base = relDir
+			rule.source = fmt.Sprintf("%s:%d", path.Join(relDir, name), lineNum)
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// matches reports whether a rule applies to a path relative to the walk root
+func (r *ignoreRule) matches(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	// Rules only apply below the directory of their ignore file
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		relPath = relPath[len(r.base)+1:]
+	}
+	return globMatch(r.pattern, relPath)
+}
+
+// match returns the rule deciding whether a path is ignored (the last matching rule wins),
+// or nil when no rule matches
+func (m *ignoreMatcher) match(relPath string, isDir bool) *ignoreRule {
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		if m.rules[i].matches(relPath, isDir) {
+			return &m.rules[i]
+		}
+	}
+	return nil
+}
+
+// ignored reports whether a path is excluded by the ignore files, logging the deciding rule
+func (m *ignoreMatcher) ignored(relPath string, isDir bool) bool {
+	if m == nil || relPath == "." {
+		return false
+	}
+	rule := m.match(relPath, isDir)
+	if rule == nil {
+		return false
+	}
+	if rule.negate {
+		DebugLog("Including %s: re-included by %s (!%s)\n", relPath, rule.source, rule.pattern)
+		return false
+	}
+	DebugLog("Skipping %s: ignored by %s (%s)\n", relPath, rule.source, rule.pattern)
+	return true
+}
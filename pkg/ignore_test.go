@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		ok      bool
+		pattern string
+		negate  bool
+		dirOnly bool
+	}{
+		{"", false, "", false, false},
+		{"# comment", false, "", false, false},
+		{"*.log", true, "*.log", false, false},
+		{"build/", true, "build", false, true},
+		{"!keep.log", true, "keep.log", true, false},
+		{"\\!important", true, "!important", false, false},
+		{"\\#hash", true, "#hash", false, false},
+		{"**/vendor", true, "vendor", false, false},
+		{"docs/**/*.c", true, "docs/**/*.c", false, false},
+		{"vendor/**", true, "vendor/**/*", false, false},
+		{"trailing   ", true, "trailing", false, false},
+		{"name\\ ", true, "name ", false, false},
+	}
+
+	for _, tt := range tests {
+		rule, ok := parseIgnoreLine(tt.line)
+		if ok != tt.ok {
+			t.Errorf("parseIgnoreLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if rule.pattern != tt.pattern || rule.negate != tt.negate || rule.dirOnly != tt.dirOnly {
+			t.Errorf("parseIgnoreLine(%q) = {%q negate=%v dirOnly=%v}, want {%q negate=%v dirOnly=%v}",
+				tt.line, rule.pattern, rule.negate, rule.dirOnly, tt.pattern, tt.negate, tt.dirOnly)
+		}
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile(t, filepath.Join(tmpDir, ".gitignore"), "*.log\nbuild/\n/root_only.c\n")
+	writeFile(t, filepath.Join(tmpDir, ".plagicheckignore"), "!keep.log\n")
+	writeFile(t, filepath.Join(tmpDir, "sub", ".gitignore"), "generated.c\n")
+
+	m := &ignoreMatcher{}
+	m.loadDir(tmpDir, "")
+	m.loadDir(filepath.Join(tmpDir, "sub"), "sub")
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"sub/trace.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"root_only.c", false, true},
+		{"sub/root_only.c", false, false},
+		{"sub/generated.c", false, true},
+		{"generated.c", false, false},
+		{"main.c", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+
+	var nilMatcher *ignoreMatcher
+	if nilMatcher.ignored("debug.log", false) {
+		t.Error("a nil matcher should not ignore any path")
+	}
+}
+
+func TestGenerateWFP_IgnoreFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+	writeFile(t, filepath.Join(tmpDir, ".gitignore"), "node_modules/\nbuild/\n")
+	writeFile(t, filepath.Join(tmpDir, "main.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "node_modules", "lib", "index.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "build", "out.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "src", ".plagicheckignore"), "*_test.c\n")
+	writeFile(t, filepath.Join(tmpDir, "src", "util.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "src", "util_test.c"), source)
+
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 2}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	out := buf.String()
+	for _, name := range []string{"main.c", "util.c"} {
+		if !strings.Contains(out, name) {
+			t.Errorf("expected %s in WFP", name)
+		}
+	}
+	for _, name := range []string{"index.c", "out.c", "util_test.c"} {
+		if strings.Contains(out, name) {
+			t.Errorf("expected %s to be ignored", name)
+		}
+	}
+
+	// As in git, "dir/**" does not exclude dir itself, negations re-include files under it
+	writeFile(t, filepath.Join(tmpDir, ".plagicheckignore"), "vendor/**\n!vendor/keep.c\n")
+	writeFile(t, filepath.Join(tmpDir, "vendor", "keep.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "vendor", "drop.c"), source)
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 2}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, ",vendor/keep.c\n") || strings.Contains(out, "drop.c") {
+		t.Errorf("expected vendor/keep.c only from vendor:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 2, NoIgnore: true}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if got := strings.Count(buf.String(), "file="); got != 7 {
+		t.Errorf("expected 7 files with NoIgnore, got %d", got)
+	}
+}
+
+// writeFile creates a file and its parent directories
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,352 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Version of the index file format
+const IndexVersion = 1
+
+// CorpusIndex is a local reference corpus: an inverted index of the snippet hashes of
+// previously fingerprinted code, used instead of the knowledge base by ScanIndex
+type CorpusIndex struct {
+	Version  int
+	Mode     string // WFP mode of the indexed fingerprints
+	Files    []IndexedFile
+	Postings map[uint32][]IndexPosting // Occurrences of every hash
+
+	md5s map[string][]int // Files by MD5, built on load
+}
+
+// IndexedFile is a file of a corpus index
+type IndexedFile struct {
+	Path   string // Path recorded in the WFP, prefixed with its input directory unless read from a WFP file
+	Source string // Directory or WFP file the file was indexed from
+	MD5    string
+	Lines  int // Last line holding hashes
+}
+
+// IndexPosting is an occurrence of a hash in an indexed file
+type IndexPosting struct {
+	File uint32
+	Line uint32
+}
+
+// NewIndex returns an empty index of fingerprints generated in a WFP mode
+func NewIndex(mode string) *CorpusIndex {
+	return &CorpusIndex{Version: IndexVersion, Mode: mode, Postings: make(map[uint32][]IndexPosting), md5s: make(map[string][]int)}
+}
+
+// LoadIndex reads an index file
+func LoadIndex(fileName string) (*CorpusIndex, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	idx := &CorpusIndex{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(idx); err != nil {
+		return nil, fmt.Errorf("error reading index %s: %v", fileName, err)
+	}
+	if idx.Version != IndexVersion {
+		return nil, fmt.Errorf("index %s has format version %d, expected %d", fileName, idx.Version, IndexVersion)
+	}
+	if idx.Postings == nil {
+		idx.Postings = make(map[uint32][]IndexPosting)
+	}
+	idx.md5s = make(map[string][]int)
+	for i, f := range idx.Files {
+		idx.md5s[f.MD5] = append(idx.md5s[f.MD5], i)
+	}
+	DebugLog("Loaded index %s: %d files, %d hashes\n", fileName, len(idx.Files), len(idx.Postings))
+	return idx, nil
+}
+
+// Save writes the index to a file, replacing it only once it is completely written
+func (idx *CorpusIndex) Save(fileName string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), filepath.Base(fileName)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	err = gob.NewEncoder(w).Encode(idx)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing index %s: %v", fileName, err)
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// Add fingerprints the inputs (directories, source files or WFP files) and adds them to the
+// index. Files of directories and source files are recorded under their input path, files of WFP
+// files under the path in the WFP; files already indexed from the same input under the same
+// path are replaced.
+func (idx *CorpusIndex) Add(ctx context.Context, inputs []string, opts WFPOptions) error {
+	for _, input := range inputs {
+		entries, err := idx.readInput(ctx, input, opts)
+		if err != nil {
+			return err
+		}
+		source := filepath.ToSlash(filepath.Clean(input))
+		idx.remove(source, entries)
+		for _, entry := range entries {
+			file := uint32(len(idx.Files))
+			idx.Files = append(idx.Files, IndexedFile{Path: entry.FilePath, Source: source, MD5: entry.MD5Hex, Lines: entry.TotalLines})
+			idx.md5s[entry.MD5Hex] = append(idx.md5s[entry.MD5Hex], int(file))
+			for i, hash := range entry.Hashes {
+				idx.Postings[hash] = append(idx.Postings[hash], IndexPosting{File: file, Line: entry.Lines[i]})
+			}
+		}
+		DebugLog("Indexed %d files from %s\n", len(entries), input)
+	}
+	return nil
+}
+
+// readInput returns the fingerprints of an index input
+func (idx *CorpusIndex) readInput(ctx context.Context, input string, opts WFPOptions) ([]*models.WFPData, error) {
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, fmt.Errorf("error accessing path: %v", err)
+	}
+
+	var entries []*models.WFPData
+	if !info.IsDir() && strings.HasSuffix(strings.ToLower(input), ".wfp") {
+		file, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		entries, err = readWFP(file, true)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading WFP file %s: %v", input, err)
+		}
+	} else {
+		prefix := input
+		if !info.IsDir() {
+			prefix = filepath.Dir(input)
+		}
+		if opts.PathPrefix = filepath.ToSlash(filepath.Clean(prefix)); opts.PathPrefix == "." {
+			opts.PathPrefix = ""
+		}
+		if entries, err = fingerprintTree(ctx, input, opts); err != nil {
+			return nil, fmt.Errorf("error fingerprinting %s: %v", input, err)
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.Mode != idx.Mode {
+			return nil, fmt.Errorf("%s was fingerprinted in %s mode, the index holds %s fingerprints", entry.FilePath, modeName(entry.Mode), modeName(idx.Mode))
+		}
+	}
+	return entries, nil
+}
+
+// remove drops the files indexed from source under the paths of new entries. WFP inputs
+// record paths relative to their own root, so the same path may come from several inputs.
+func (idx *CorpusIndex) remove(source string, entries []*models.WFPData) {
+	paths := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		paths[entry.FilePath] = true
+	}
+	newID := make([]int, len(idx.Files))
+	var kept []IndexedFile
+	for i, f := range idx.Files {
+		if f.Source == source && paths[f.Path] {
+			newID[i] = -1
+			continue
+		}
+		newID[i] = len(kept)
+		kept = append(kept, f)
+	}
+	if len(kept) == len(idx.Files) {
+		return
+	}
+	DebugLog("Replacing %d indexed files\n", len(idx.Files)-len(kept))
+
+	idx.Files = kept
+	for hash, postings := range idx.Postings {
+		var remapped []IndexPosting
+		for _, p := range postings {
+			if id := newID[p.File]; id >= 0 {
+				remapped = append(remapped, IndexPosting{File: uint32(id), Line: p.Line})
+			}
+		}
+		if len(remapped) == 0 {
+			delete(idx.Postings, hash)
+		} else {
+			idx.Postings[hash] = remapped
+		}
+	}
+	idx.md5s = make(map[string][]int)
+	for i, f := range idx.Files {
+		idx.md5s[f.MD5] = append(idx.md5s[f.MD5], i)
+	}
+}
+
+// modeName returns the display name of a WFP mode
+func modeName(mode string) string {
+	if mode == ModeStandard {
+		return "standard"
+	}
+	return mode
+}
+
+// ScanIndex matches the files of a WFP file against an index instead of the knowledge base.
+// Results have the same format as ScanWFPFile: a full_file match for files indexed with the
+// same MD5, otherwise the code_snippet match of the indexed file sharing the most hashes.
+func ScanIndex(idx *CorpusIndex, wfpFilePath string, minHits int, progress io.Writer) (map[string][]*models.MatchResult, error) {
+	return scanIndex(idx, wfpFilePath, minHits, 1, progress)
+}
+
+// ScanIndexAll is ScanIndex reporting a code_snippet match for every indexed file sharing at
+// least minHits hashes with a file, best first, instead of the best one only
+func ScanIndexAll(idx *CorpusIndex, wfpFilePath string, minHits int, progress io.Writer) (map[string][]*models.MatchResult, error) {
+	return scanIndex(idx, wfpFilePath, minHits, 0, progress)
+}
+
+// scanIndex matches the files of a WFP file against an index, reporting up to limit matches
+// per file (no limit if 0)
+func scanIndex(idx *CorpusIndex, wfpFilePath string, minHits, limit int, progress io.Writer) (map[string][]*models.MatchResult, error) {
+	file, err := os.Open(wfpFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading WFP file: %v", err)
+	}
+	entries, err := readWFP(file, true)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading WFP file: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Mode != idx.Mode {
+			return nil, fmt.Errorf("%s was fingerprinted in %s mode, the index holds %s fingerprints", entry.FilePath, modeName(entry.Mode), modeName(idx.Mode))
+		}
+	}
+
+	results := make(map[string][]*models.MatchResult)
+	for i, entry := range entries {
+		key := entry.FilePath
+		if _, exists := results[key]; exists {
+			key = fmt.Sprintf("%s [%s]", entry.FilePath, entry.MD5Hex)
+		}
+
+		matches, err := idx.match(entry, minHits, limit)
+		if err != nil {
+			DebugLog("%s: %v\n", entry.FilePath, err)
+			results[key] = []*models.MatchResult{{MatchType: "no_match"}}
+		} else {
+			for _, match := range matches {
+				applyIdentifications(entry, match)
+			}
+			results[key] = matches
+		}
+		if progress != nil {
+			fmt.Fprintf(progress, "progress:%d/%d\n", i+1, len(entries))
+		}
+	}
+	return results, nil
+}
+
+// match returns the matches of a file in the index, best first: a full_file match for an
+// indexed file of the same MD5, otherwise up to limit code_snippet matches (no limit if 0)
+func (idx *CorpusIndex) match(entry *models.WFPData, minHits, limit int) ([]*models.MatchResult, error) {
+	if isTemplateFile(entry.MD5Hex) {
+		return nil, fmt.Errorf("file is part of the template")
+	}
+	if files := idx.md5s[entry.MD5Hex]; len(files) > 0 {
+		ref := idx.Files[files[0]]
+		return []*models.MatchResult{{
+			MatchType:     "full_file",
+			Instances:     len(files),
+			ReferenceURL:  ref.Source,
+			ReferenceFile: ref.Path,
+			ReferenceMD5:  ref.MD5,
+			Coverage:      100,
+		}}, nil
+	}
+
+	subtractTemplate(entry)
+	hits := make(map[uint32]int)
+	ranges := make(map[uint32][]models.Range)
+	seen := make(map[uint32]bool)
+	for i, hash := range entry.Hashes {
+		counted := make(map[uint32]bool)
+		for _, p := range idx.Postings[hash] {
+			if !seen[hash] && !counted[p.File] {
+				counted[p.File] = true
+				hits[p.File]++
+			}
+			ranges[p.File] = append(ranges[p.File], models.Range{From: int(entry.Lines[i]), To: int(entry.Lines[i]), Oss: int(p.Line)})
+		}
+		seen[hash] = true
+	}
+
+	// Best candidate: most hits, then lowest file ID (earliest indexed)
+	candidates := make([]uint32, 0, len(hits))
+	for file := range hits {
+		candidates = append(candidates, file)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no matches found")
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if hits[candidates[i]] != hits[candidates[j]] {
+			return hits[candidates[i]] > hits[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	if best := candidates[0]; hits[best] < minHits {
+		return nil, fmt.Errorf("insufficient hits: %d (minimum required: %d)", hits[best], minHits)
+	}
+
+	var matches []*models.MatchResult
+	for _, file := range candidates {
+		if hits[file] < minHits || (limit > 0 && len(matches) == limit) {
+			break
+		}
+		merged := FilterValidRanges(MergeRanges(ranges[file], RangeMergeTolerance))
+		if len(merged) == 0 {
+			continue
+		}
+		ref := idx.Files[file]
+		targetLines, refLines := FormatRanges(merged)
+		matches = append(matches, &models.MatchResult{
+			MatchType:     "code_snippet",
+			TargetLines:   targetLines,
+			SourceLines:   refLines,
+			Instances:     len(idx.md5s[ref.MD5]),
+			ReferenceURL:  ref.Source,
+			ReferenceFile: ref.Path,
+			ReferenceMD5:  ref.MD5,
+			Coverage:      RangesCoverage(merged, entry.TotalLines),
+			Hits:          hits[file],
+			Ranges:        merged,
+		})
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no valid ranges found (all ranges span single line)")
+	}
+	return matches, nil
+}
@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorpusIndex(t *testing.T) {
+	LoadFilters("")
+	ctx := context.Background()
+	dir := t.TempDir()
+	old := filepath.Join(dir, "2024")
+	writeFile(t, filepath.Join(old, "alice", "main.c"), sampleCode("alice", 10))
+	writeFile(t, filepath.Join(old, "bob", "main.c"), sampleCode("bob", 10))
+
+	idx := NewIndex(ModeStandard)
+	if err := idx.Add(ctx, []string{old}, WFPOptions{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	indexFile := filepath.Join(dir, "corpus.idx")
+	if err := idx.Save(indexFile); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	idx, err := LoadIndex(indexFile)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+	// Paths are recorded under the input path (relative, as every WFP path)
+	prefix := strings.TrimPrefix(filepath.ToSlash(old), "/")
+	if len(idx.Files) != 2 || idx.Files[0].Path != prefix+"/alice/main.c" || idx.Files[0].Source != filepath.ToSlash(old) {
+		t.Fatalf("unexpected indexed files: %+v", idx.Files)
+	}
+
+	// A new submission copying part of bob's code, and a verbatim copy of alice's file
+	target := filepath.Join(dir, "2025")
+	writeFile(t, filepath.Join(target, "carol", "main.c"), sampleCode("carol", 4)+sampleCode("bob", 6))
+	writeFile(t, filepath.Join(target, "dave", "main.c"), sampleCode("alice", 10))
+	wfpFile := filepath.Join(dir, "target.wfp")
+	out, _ := os.Create(wfpFile)
+	if err := GenerateWFP(ctx, target, out, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP() error = %v", err)
+	}
+	out.Close()
+
+	results, err := ScanIndex(idx, wfpFile, 3, nil)
+	if err != nil {
+		t.Fatalf("ScanIndex() error = %v", err)
+	}
+	carol := results["carol/main.c"][0]
+	if carol.MatchType != "code_snippet" || carol.ReferenceFile != prefix+"/bob/main.c" || carol.TargetLines == "" {
+		t.Errorf("unexpected carol match: %+v", carol)
+	}
+	if dave := results["dave/main.c"][0]; dave.MatchType != "full_file" || dave.ReferenceFile != prefix+"/alice/main.c" {
+		t.Errorf("unexpected dave match: %+v", dave)
+	}
+
+	// Adding a directory again replaces its files instead of duplicating them
+	writeFile(t, filepath.Join(old, "bob", "main.c"), strings.Repeat("#define BOB_RENAMED_TABLE_ENTRY(x) lookup_table[x]\n", 20))
+	if err := idx.Add(ctx, []string{old}, WFPOptions{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(idx.Files) != 2 {
+		t.Fatalf("expected 2 indexed files after re-adding, got %d", len(idx.Files))
+	}
+	results, _ = ScanIndex(idx, wfpFile, 3, nil)
+	if carol := results["carol/main.c"][0]; carol.ReferenceFile == prefix+"/bob/main.c" {
+		t.Errorf("replaced file still matches: %+v", carol)
+	}
+
+	// Fingerprints of another mode are refused
+	if err := idx.Add(ctx, []string{target}, WFPOptions{Mode: ModeTokens}); err == nil {
+		t.Errorf("Add() accepted tokens mode fingerprints into a standard index")
+	}
+}
+
+func TestCorpusIndex_WFPInputs(t *testing.T) {
+	LoadFilters("")
+	ctx := context.Background()
+	dir := t.TempDir()
+	// Two years of submissions fingerprinted elsewhere, both holding src/main.c
+	var inputs []string
+	for _, year := range []string{"y2023", "y2024"} {
+		tree := filepath.Join(dir, year)
+		writeFile(t, filepath.Join(tree, "src", "main.c"), messageTable(year, 20))
+		wfpFile := filepath.Join(dir, year+".wfp")
+		out, _ := os.Create(wfpFile)
+		if err := GenerateWFP(ctx, tree, out, WFPOptions{}); err != nil {
+			t.Fatalf("GenerateWFP() error = %v", err)
+		}
+		out.Close()
+		inputs = append(inputs, wfpFile)
+	}
+
+	idx := NewIndex(ModeStandard)
+	if err := idx.Add(ctx, inputs, WFPOptions{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(idx.Files) != 2 || idx.Files[0].Source == idx.Files[1].Source {
+		t.Fatalf("expected src/main.c from both WFP files, got %+v", idx.Files)
+	}
+
+	// Adding a WFP again only replaces the files it holds
+	if err := idx.Add(ctx, inputs[1:], WFPOptions{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if len(idx.Files) != 2 || idx.Files[0].Source != filepath.ToSlash(inputs[0]) || idx.Files[1].Source != filepath.ToSlash(inputs[1]) {
+		t.Errorf("unexpected indexed files after re-adding: %+v", idx.Files)
+	}
+}
+
+// messageTable returns a C file of distinct lines
+func messageTable(seed string, lines int) string {
+	var b strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&b, "static const char *%s_msg_%d = \"%s: entry %d of the %s table\";\n", seed, i, seed, i*i+7, seed)
+	}
+	return b.String()
+}
+
+func TestScanIndexAll(t *testing.T) {
+	LoadFilters("")
+	ctx := context.Background()
+	dir := t.TempDir()
+	upstream := filepath.Join(dir, "upstream")
+	writeFile(t, filepath.Join(upstream, "parser.c"), messageTable("parser", 40))
+	writeFile(t, filepath.Join(upstream, "lexer.c"), messageTable("lexer", 40))
+	writeFile(t, filepath.Join(upstream, "unrelated.c"), strings.Repeat("#define UNRELATED_TABLE_ENTRY(x) lookup_table[x]\n", 20))
+	idx := NewIndex(ModeStandard)
+	if err := idx.Add(ctx, []string{upstream}, WFPOptions{}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// A vendor file made of code from two upstream files
+	deliverable := filepath.Join(dir, "deliverable")
+	writeFile(t, filepath.Join(deliverable, "engine.c"), messageTable("vendor", 15)+messageTable("lexer", 20)+messageTable("parser", 30))
+	wfpFile := filepath.Join(dir, "deliverable.wfp")
+	out, _ := os.Create(wfpFile)
+	if err := GenerateWFP(ctx, deliverable, out, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP() error = %v", err)
+	}
+	out.Close()
+
+	results, err := ScanIndexAll(idx, wfpFile, 3, nil)
+	if err != nil {
+		t.Fatalf("ScanIndexAll() error = %v", err)
+	}
+	matches := results["engine.c"]
+	if len(matches) != 2 {
+		t.Fatalf("expected matches of parser.c and lexer.c, got %+v", matches)
+	}
+	prefix := strings.TrimPrefix(filepath.ToSlash(upstream), "/")
+	if matches[0].ReferenceFile != prefix+"/parser.c" || matches[1].ReferenceFile != prefix+"/lexer.c" || matches[0].Hits < matches[1].Hits {
+		t.Errorf("unexpected match order: %s, %s", matches[0].ReferenceFile, matches[1].ReferenceFile)
+	}
+	// The parser table starts at line 36 of engine.c and at line 1 upstream (hashes are
+	// recorded at the line their window ends on, give or take a line)
+	var target, source int
+	fmt.Sscanf(matches[0].TargetLines, "%d-", &target)
+	fmt.Sscanf(matches[0].SourceLines, "%d-", &source)
+	if offset := target - source; matches[0].MatchType != "code_snippet" || target < 35 || offset < 34 || offset > 36 {
+		t.Errorf("unexpected aligned ranges %s / %s", matches[0].TargetLines, matches[0].SourceLines)
+	}
+
+	// ScanIndex reports the best match only
+	results, _ = ScanIndex(idx, wfpFile, 3, nil)
+	if len(results["engine.c"]) != 1 || results["engine.c"][0].ReferenceFile != matches[0].ReferenceFile {
+		t.Errorf("unexpected ScanIndex results: %+v", results["engine.c"])
+	}
+}
@@ -0,0 +1,304 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// WFP modes. Standard fingerprints are compatible with the knowledge base; the other modes
+// are marked with a mode= line in every file block and are only meant for local comparisons.
+const (
+	ModeStandard      = ""
+	ModeStripComments = "strip-comments" // Comments removed before winnowing
+	ModeStripLiterals = "strip-literals" // Comments and string literals removed before winnowing
+	ModeTokens        = "tokens"         // Tokens winnowed with identifiers and literals as placeholders
+)
+
+// ParseMode validates a WFP mode name ("standard" stands for the default mode)
+func ParseMode(name string) (string, error) {
+	switch name {
+	case "", "standard":
+		return ModeStandard, nil
+	case ModeStripComments, ModeStripLiterals, ModeTokens:
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown WFP mode %q (expected standard, %s, %s or %s)", name, ModeStripComments, ModeStripLiterals, ModeTokens)
+}
+
+// quoteSyntax is a string literal delimiter
+type quoteSyntax struct {
+	delim     string
+	escapes   bool // Backslash escapes the next character
+	multiline bool // The literal may span lines
+}
+
+// lexSyntax describes the comments and string literals of a language family
+type lexSyntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	quotes        []quoteSyntax // Longest delimiters first
+	hashWordStart bool          // "#" only starts a comment at the beginning of a word (shell)
+	regexLiterals bool          // "/" starts a regular expression where an operand is expected (JavaScript)
+	charQuotes    bool          // "'" only quotes a single character or escape sequence (Rust lifetimes)
+}
+
+var (
+	cSyntax = &lexSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quoteSyntax{{`"`, true, false}, {`'`, true, false}},
+	}
+	goSyntax = &lexSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quoteSyntax{{`"`, true, false}, {`'`, true, false}, {"`", false, true}},
+	}
+	jsSyntax = &lexSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quoteSyntax{{`"`, true, false}, {`'`, true, false}, {"`", true, true}},
+		regexLiterals: true,
+	}
+	rustSyntax = &lexSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quoteSyntax{{`"`, true, true}, {`'`, true, false}},
+		charQuotes:    true,
+	}
+	phpSyntax = &lexSyntax{
+		lineComments:  []string{"//", "#"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []quoteSyntax{{`"`, true, true}, {`'`, true, true}},
+	}
+	pythonSyntax = &lexSyntax{
+		lineComments: []string{"#"},
+		quotes: []quoteSyntax{{`"""`, true, true}, {`'''`, true, true},
+			{`"`, true, false}, {`'`, true, false}},
+	}
+	shellSyntax = &lexSyntax{
+		lineComments:  []string{"#"},
+		quotes:        []quoteSyntax{{`"`, true, true}, {`'`, false, true}},
+		hashWordStart: true,
+	}
+)
+
+// Lexical syntax by file extension
+var lexSyntaxes = map[string]*lexSyntax{
+	".c": cSyntax, ".h": cSyntax, ".cc": cSyntax, ".cpp": cSyntax, ".cxx": cSyntax, ".c++": cSyntax,
+	".hh": cSyntax, ".hpp": cSyntax, ".hxx": cSyntax, ".m": cSyntax, ".mm": cSyntax,
+	".cs": cSyntax, ".java": cSyntax, ".kt": cSyntax, ".kts": cSyntax, ".scala": cSyntax,
+	".groovy": cSyntax, ".swift": cSyntax, ".dart": cSyntax,
+	".rs": rustSyntax,
+	".go": goSyntax,
+	".js": jsSyntax, ".jsx": jsSyntax, ".mjs": jsSyntax, ".cjs": jsSyntax,
+	".ts": jsSyntax, ".tsx": jsSyntax, ".mts": jsSyntax, ".cts": jsSyntax,
+	".php": phpSyntax,
+	".py":  pythonSyntax, ".pyw": pythonSyntax, ".pyi": pythonSyntax,
+	".sh": shellSyntax, ".bash": shellSyntax, ".zsh": shellSyntax, ".ksh": shellSyntax,
+}
+
+// stripCode removes the comments, and the string literals too if literals is set, of a
+// source file in a supported language. Newlines are kept so that hashes keep their line
+// numbers. Files in other languages are returned unchanged.
+func stripCode(name string, text []byte, literals bool) []byte {
+	syntax := lexSyntaxes[strings.ToLower(path.Ext(name))]
+	if syntax == nil {
+		return text
+	}
+
+	out := make([]byte, 0, len(text))
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		if prefix := syntax.lineComment(text, i); prefix != "" {
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				break
+			}
+			i += end
+			continue
+		}
+
+		if block, ok := syntax.blockComment(rest); ok {
+			end := bytes.Index(rest[len(block[0]):], []byte(block[1]))
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += len(block[0]) + len(block[1])
+			}
+			out = appendNewlines(out, rest[:end])
+			i += end
+			continue
+		}
+
+		if end := syntax.regexEnd(text, i); end > 0 {
+			if literals {
+				out = appendNewlines(out, rest[:end])
+			} else {
+				out = append(out, rest[:end]...)
+			}
+			i += end
+			continue
+		}
+
+		if quote, ok := syntax.quote(rest); ok {
+			end := quote.literalEnd(rest)
+			if literals {
+				out = appendNewlines(out, rest[:end])
+			} else {
+				out = append(out, rest[:end]...)
+			}
+			i += end
+			continue
+		}
+
+		out = append(out, text[i])
+		i++
+	}
+	return out
+}
+
+// lineComment returns the line comment prefix starting at text[i], or ""
+func (s *lexSyntax) lineComment(text []byte, i int) string {
+	for _, prefix := range s.lineComments {
+		if !bytes.HasPrefix(text[i:], []byte(prefix)) {
+			continue
+		}
+		// In shell, "#" within a word is not a comment ($#, ${#var}, a#b)
+		if s.hashWordStart && i > 0 && !strings.ContainsRune(" \t\r\n;&|()", rune(text[i-1])) {
+			continue
+		}
+		return prefix
+	}
+	return ""
+}
+
+// blockComment returns the block comment delimiters starting at the beginning of text
+func (s *lexSyntax) blockComment(text []byte) ([2]string, bool) {
+	for _, block := range s.blockComments {
+		if bytes.HasPrefix(text, []byte(block[0])) {
+			return block, true
+		}
+	}
+	return [2]string{}, false
+}
+
+// quote returns the string literal delimiter starting at the beginning of text
+func (s *lexSyntax) quote(text []byte) (quoteSyntax, bool) {
+	for _, q := range s.quotes {
+		if bytes.HasPrefix(text, []byte(q.delim)) && (q.delim != "'" || !s.charQuotes || isCharLiteral(text)) {
+			return q, true
+		}
+	}
+	return quoteSyntax{}, false
+}
+
+// isCharLiteral reports whether the quote at the beginning of text closes after one
+// character ('a', 'é') or escape sequence ('\n', '\u{1F600}'), unlike a Rust lifetime
+// ('a, 'static)
+func isCharLiteral(text []byte) bool {
+	if len(text) > 1 && text[1] == '\\' {
+		// The longest escape sequence is \u{10FFFF}
+		for i := 3; i < len(text) && i <= 10; i++ {
+			switch text[i] {
+			case '\'':
+				return true
+			case '\n':
+				return false
+			}
+		}
+		return false
+	}
+	r, size := utf8.DecodeRune(text[1:])
+	return size > 0 && r != '\n' && r != '\'' && 1+size < len(text) && text[1+size] == '\''
+}
+
+// regexKeywords are the keywords after which "/" starts a regular expression
+var regexKeywords = toSet("return", "typeof", "instanceof", "in", "of", "new", "delete", "void",
+	"throw", "case", "do", "else", "yield", "await")
+
+// regexEnd returns the length of the regular expression literal starting at text[i], flags
+// included, or 0 if there is none: "/" is a division after an operand (identifier, number,
+// literal, closing bracket) and starts a regular expression elsewhere
+func (s *lexSyntax) regexEnd(text []byte, i int) int {
+	if !s.regexLiterals || text[i] != '/' {
+		return 0
+	}
+	j := i - 1
+	for j >= 0 && strings.IndexByte(" \t\r\n", text[j]) >= 0 {
+		j--
+	}
+	if j >= 0 {
+		switch c := text[j]; {
+		case c == ')' || c == ']' || c == '"' || c == '\'' || c == '`':
+			return 0
+		case isIdentByte(c):
+			start := j
+			for start > 0 && isIdentByte(text[start-1]) {
+				start--
+			}
+			if !regexKeywords[string(text[start:j+1])] {
+				return 0
+			}
+		}
+	}
+
+	inClass := false
+	for k := i + 1; k < len(text); k++ {
+		switch text[k] {
+		case '\\':
+			k++
+		case '\n':
+			return 0
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if inClass {
+				continue
+			}
+			if k == i+1 {
+				return 0
+			}
+			k++
+			for k < len(text) && isIdentByte(text[k]) {
+				k++
+			}
+			return k - i
+		}
+	}
+	return 0
+}
+
+// literalEnd returns the length of the string literal at the beginning of text.
+// Unterminated single-line literals end at the end of the line.
+func (q quoteSyntax) literalEnd(text []byte) int {
+	for i := len(q.delim); i < len(text); i++ {
+		switch {
+		case q.escapes && text[i] == '\\':
+			i++
+		case text[i] == '\n' && !q.multiline:
+			return i
+		case bytes.HasPrefix(text[i:], []byte(q.delim)):
+			return i + len(q.delim)
+		}
+	}
+	return len(text)
+}
+
+// appendNewlines appends the newlines of removed text
+func appendNewlines(out, removed []byte) []byte {
+	for n := bytes.Count(removed, []byte("\n")); n > 0; n-- {
+		out = append(out, '\n')
+	}
+	return out
+}
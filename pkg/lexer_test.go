@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		text     string
+		literals bool
+		want     string
+	}{
+		{"c line comment", "a.c", "int a; // note\nint b;\n", false, "int a; \nint b;\n"},
+		{"c block comment keeps lines", "a.c", "/* a\n * b\n */\nint a;\n", false, "\n\n\nint a;\n"},
+		{"comment marker in string", "a.c", "char *u = \"http://x\"; // c\n", false, "char *u = \"http://x\"; \n"},
+		{"escaped quote", "a.java", "s = \"a\\\"//b\"; // c\n", false, "s = \"a\\\"//b\"; \n"},
+		{"c literals", "a.c", "puts(\"hi /* x */\"); c = 'x';\n", true, "puts(); c = ;\n"},
+		{"go raw string", "a.go", "s := `a\n// b`\n", true, "s := \n\n"},
+		{"js template", "a.ts", "const s = `x ${y}\n`; // c\n", true, "const s = \n; \n"},
+		{"python", "a.py", "def f():\n    \"\"\"Doc\n    string\"\"\"\n    return '#' # c\n", false,
+			"def f():\n    \"\"\"Doc\n    string\"\"\"\n    return '#' \n"},
+		{"python literals", "a.py", "def f():\n    \"\"\"Doc\n    string\"\"\"\n    return '#' # c\n", true,
+			"def f():\n    \n\n    return  \n"},
+		{"shell", "a.sh", "echo $# ${#x} # count\n# header\n", false, "echo $# ${#x} \n\n"},
+		{"js regex", "a.js", "s = s.replace(/\\/*$/, ''); // c\nfunction important() { return 1; }\n", false,
+			"s = s.replace(/\\/*$/, ''); \nfunction important() { return 1; }\n"},
+		{"js regex literals", "a.ts", "if (/[/'\"]/g.test(s)) return /x/i;\n", true, "if (.test(s)) return ;\n"},
+		{"js division", "a.js", "x = a / b / 2; y = (a) / c; // c\n", false, "x = a / b / 2; y = (a) / c; \n"},
+		{"rust lifetimes", "a.rs", "fn f<'a>(x: &'a str) -> &'static str { x } // c\n", true,
+			"fn f<'a>(x: &'a str) -> &'static str { x } \n"},
+		{"rust chars", "a.rs", "let c = ['x', '\\'', '\\u{1F600}', 'é'];\n", true, "let c = [, , , ];\n"},
+		{"unknown language", "a.txt", "// kept\n", true, "// kept\n"},
+	}
+	for _, tt := range tests {
+		if got := string(stripCode(tt.file, []byte(tt.text), tt.literals)); got != tt.want {
+			t.Errorf("%s: stripCode() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprintData_Mode(t *testing.T) {
+	code := "int add(int a, int b) {\n\treturn a + b + compute_offset(a, b) * scale_factor;\n}\n" +
+		"int sub(int a, int b) {\n\treturn a - b - compute_offset(b, a) * scale_factor;\n}\n" +
+		"int mul(int a, int b) {\n\treturn a * b * compute_offset(a, a) + scale_factor;\n}\n"
+	commented := "// Copyright (C) Someone Else, all rights reserved\n" +
+		strings.ReplaceAll(code, "{\n", "{ /* rewritten comment */\n")
+	hashes := func(wfp string) string {
+		var lines []string
+		for _, line := range strings.Split(wfp, "\n") {
+			if hashLinePrefix.MatchString(line) {
+				_, value, _ := strings.Cut(line, "=")
+				lines = append(lines, value)
+			}
+		}
+		return strings.Join(lines, ",")
+	}
+
+	standard := fingerprintData("a.c", []byte(code), WFPOptions{})
+	if strings.Contains(standard, "mode=") {
+		t.Errorf("standard WFP has a mode line:\n%s", standard)
+	}
+
+	opts := WFPOptions{Mode: ModeStripComments}
+	plain := fingerprintData("a.c", []byte(code), opts)
+	rewritten := fingerprintData("a.c", []byte(commented), opts)
+	if !strings.Contains(plain, "\nmode="+ModeStripComments+"\n") {
+		t.Errorf("WFP has no mode line:\n%s", plain)
+	}
+	if hashes(plain) == "" || hashes(plain) != hashes(rewritten) {
+		t.Errorf("rewritten comments changed the hashes:\n%s\n%s", plain, rewritten)
+	}
+	if hashes(fingerprintData("a.c", []byte(commented), WFPOptions{})) == hashes(standard) {
+		t.Errorf("standard mode ignored the comments")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for name, want := range map[string]string{"": ModeStandard, "standard": ModeStandard,
+		"strip-comments": ModeStripComments, "strip-literals": ModeStripLiterals} {
+		if got, err := ParseMode(name); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseMode("tokens-typo"); err == nil {
+		t.Errorf("ParseMode() accepted an unknown mode")
+	}
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// LicenseMapping assigns licenses to reference URLs or file MD5s (local alternative to the KB tables)
+type LicenseMapping struct {
+	URL      string   `json:"url,omitempty"` // Reference URL pattern, '*' matches any sequence
+	MD5      string   `json:"md5,omitempty"` // MD5 of the reference file
+	Licenses []string `json:"licenses"`
+}
+
+var licenseMap []LicenseMapping
+
+var licenseCache = make(map[string][]string)
+var licenseCacheMutex sync.Mutex
+
+// spdxIDs lists the SPDX identifiers recognized when normalizing license names
+var spdxIDs = []string{
+	"0BSD", "AFL-3.0", "AGPL-3.0-only", "AGPL-3.0-or-later", "Apache-1.1", "Apache-2.0", "APSL-2.0",
+	"Artistic-2.0", "BSD-1-Clause", "BSD-2-Clause", "BSD-3-Clause", "BSD-4-Clause", "BSL-1.0",
+	"CC-BY-4.0", "CC-BY-SA-4.0", "CC0-1.0", "CDDL-1.0", "CDDL-1.1", "CPL-1.0", "ECL-2.0", "EPL-1.0",
+	"EPL-2.0", "EUPL-1.1", "EUPL-1.2", "GPL-1.0-only", "GPL-1.0-or-later", "GPL-2.0-only",
+	"GPL-2.0-or-later", "GPL-3.0-only", "GPL-3.0-or-later", "ISC", "LGPL-2.0-only", "LGPL-2.0-or-later",
+	"LGPL-2.1-only", "LGPL-2.1-or-later", "LGPL-3.0-only", "LGPL-3.0-or-later", "MIT", "MIT-0",
+	"MPL-1.1", "MPL-2.0", "MS-PL", "MS-RL", "NCSA", "OpenSSL", "OSL-3.0", "PHP-3.01", "PostgreSQL",
+	"Python-2.0", "Ruby", "SSPL-1.0", "Unlicense", "UPL-1.0", "Vim", "WTFPL", "X11", "Zlib", "ZPL-2.1",
+}
+
+// licenseAliases maps common (lowercase) license names to SPDX identifiers
+var licenseAliases = map[string]string{
+	"gpl-1.0":                            "GPL-1.0-only",
+	"gpl-1.0+":                           "GPL-1.0-or-later",
+	"gpl-2.0":                            "GPL-2.0-only",
+	"gpl-2.0+":                           "GPL-2.0-or-later",
+	"gplv2":                              "GPL-2.0-only",
+	"gpl2":                               "GPL-2.0-only",
+	"gnu general public license v2.0":    "GPL-2.0-only",
+	"gpl-3.0":                            "GPL-3.0-only",
+	"gpl-3.0+":                           "GPL-3.0-or-later",
+	"gplv3":                              "GPL-3.0-only",
+	"gpl3":                               "GPL-3.0-only",
+	"gnu general public license v3.0":    "GPL-3.0-only",
+	"lgpl-2.0":                           "LGPL-2.0-only",
+	"lgpl-2.0+":                          "LGPL-2.0-or-later",
+	"lgpl-2.1":                           "LGPL-2.1-only",
+	"lgpl-2.1+":                          "LGPL-2.1-or-later",
+	"lgplv2.1":                           "LGPL-2.1-only",
+	"lgpl-3.0":                           "LGPL-3.0-only",
+	"lgpl-3.0+":                          "LGPL-3.0-or-later",
+	"lgplv3":                             "LGPL-3.0-only",
+	"agpl-3.0":                           "AGPL-3.0-only",
+	"agpl-3.0+":                          "AGPL-3.0-or-later",
+	"agplv3":                             "AGPL-3.0-only",
+	"apache 2.0":                         "Apache-2.0",
+	"apache-2":                           "Apache-2.0",
+	"apache license 2.0":                 "Apache-2.0",
+	"apache license, version 2.0":        "Apache-2.0",
+	"apache2":                            "Apache-2.0",
+	"asl 2.0":                            "Apache-2.0",
+	"mit license":                        "MIT",
+	"expat":                              "MIT",
+	"bsd":                                "BSD-3-Clause",
+	"new bsd":                            "BSD-3-Clause",
+	"bsd-new":                            "BSD-3-Clause",
+	"bsd 3-clause":                       "BSD-3-Clause",
+	"simplified bsd":                     "BSD-2-Clause",
+	"bsd 2-clause":                       "BSD-2-Clause",
+	"mpl 2.0":                            "MPL-2.0",
+	"mozilla public license 2.0":         "MPL-2.0",
+	"eclipse public license 2.0":         "EPL-2.0",
+	"boost software license 1.0":         "BSL-1.0",
+	"zlib license":                       "Zlib",
+	"python software foundation license": "Python-2.0",
+	"psf":                                "Python-2.0",
+	"common development and distribution license 1.0": "CDDL-1.0",
+}
+
+// copyleftPrefixes identifies the SPDX license families with copyleft obligations
+var copyleftPrefixes = []string{"GPL-", "LGPL-", "AGPL-", "MPL-", "EPL-", "EUPL-", "CDDL-", "CPL-", "OSL-", "CC-BY-SA-", "MS-RL", "SSPL-"}
+
+// NormalizeLicense converts a license name to its SPDX identifier.
+// Unknown names are returned trimmed but otherwise unchanged.
+func NormalizeLicense(name string) string {
+	name = strings.TrimSpace(name)
+	lower := strings.ToLower(name)
+	for _, id := range spdxIDs {
+		if strings.ToLower(id) == lower {
+			return id
+		}
+	}
+	if id, ok := licenseAliases[lower]; ok {
+		return id
+	}
+	return name
+}
+
+// IsCopyleft reports whether an SPDX identifier belongs to a copyleft license family
+func IsCopyleft(license string) bool {
+	for _, prefix := range copyleftPrefixes {
+		if strings.HasPrefix(license, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadLicenseMap loads a local license mapping file, used instead of the KB license tables.
+// An empty fileName clears any previously loaded mapping.
+func LoadLicenseMap(fileName string) error {
+	licenseMap = nil
+	if fileName == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	var mappings []LicenseMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return fmt.Errorf("invalid license map %s: %v", fileName, err)
+	}
+	for i := range mappings {
+		mappings[i].MD5 = strings.ToLower(mappings[i].MD5)
+	}
+	licenseMap = mappings
+	DebugLog("Loaded %d license mappings from %s\n", len(licenseMap), fileName)
+	return nil
+}
+
+// GetLicenseRecords retrieves the license names stored in the KB for a file or URL key
+func GetLicenseRecords(kbName, key string) ([]string, error) {
+	cmd := exec.Command("sh", "-c", fmt.Sprintf("echo select from %s/license key %s csv hex 16 | ldb", kbName, key))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	// Each line has the format: key,source_id,license_name
+	var licenses []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		licenses = append(licenses, fields[len(fields)-1])
+	}
+	return licenses, nil
+}
+
+// lookupLicenses returns the raw license names known for a match
+func lookupLicenses(kbName string, match *models.MatchResult) []string {
+	if licenseMap != nil {
+		var licenses []string
+		for _, m := range licenseMap {
+			if (m.URL != "" && wildcardMatch(m.URL, match.ReferenceURL)) || (m.MD5 != "" && m.MD5 == match.ReferenceMD5) {
+				licenses = append(licenses, m.Licenses...)
+			}
+		}
+		return licenses
+	}
+
+	// KB license records are keyed by file MD5 and by URL MD5
+	var keys []string
+	if match.ReferenceMD5 != "" {
+		keys = append(keys, match.ReferenceMD5)
+	}
+	if match.ReferenceURL != "" {
+		keys = append(keys, fmt.Sprintf("%x", md5.Sum([]byte(match.ReferenceURL))))
+	}
+
+	var licenses []string
+	for _, key := range keys {
+		licenseCacheMutex.Lock()
+		cached, ok := licenseCache[key]
+		licenseCacheMutex.Unlock()
+		if !ok {
+			cached, _ = GetLicenseRecords(kbName, key)
+			licenseCacheMutex.Lock()
+			licenseCache[key] = cached
+			licenseCacheMutex.Unlock()
+		}
+		licenses = append(licenses, cached...)
+	}
+	return licenses
+}
+
+// enrichLicenses sets the SPDX licenses and the copyleft flag of a match
+func enrichLicenses(kbName string, match *models.MatchResult) {
+	if match == nil || match.MatchType == "no_match" {
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range lookupLicenses(kbName, match) {
+		id := NormalizeLicense(name)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		match.Licenses = append(match.Licenses, id)
+		if IsCopyleft(id) {
+			match.Copyleft = true
+		}
+	}
+	sort.Strings(match.Licenses)
+}
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestNormalizeLicense(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		copyleft bool
+	}{
+		{"mit", "MIT", false},
+		{"GPLv2", "GPL-2.0-only", true},
+		{" GPL-2.0+ ", "GPL-2.0-or-later", true},
+		{"Apache License 2.0", "Apache-2.0", false},
+		{"lgpl-2.1", "LGPL-2.1-only", true},
+		{"mpl-2.0", "MPL-2.0", true},
+		{"Some Custom License", "Some Custom License", false},
+		// Public domain dedications are not the Unlicense and are kept as written
+		{"Public Domain", "Public Domain", false},
+	}
+
+	for _, tt := range tests {
+		got := NormalizeLicense(tt.name)
+		if got != tt.expected {
+			t.Errorf("NormalizeLicense(%q) = %q, expected %q", tt.name, got, tt.expected)
+		}
+		if IsCopyleft(got) != tt.copyleft {
+			t.Errorf("IsCopyleft(%q) = %v, expected %v", got, !tt.copyleft, tt.copyleft)
+		}
+	}
+}
+
+func TestEnrichLicensesFromMap(t *testing.T) {
+	mapping := `[
+  {"url": "https://github.com/madler/zlib/*", "licenses": ["zlib license"]},
+  {"md5": "00FFFFF25AFAA0D78FF1C6F41BA7F965", "licenses": ["GPLv2", "gpl-2.0"]}
+]`
+	tmpFile := filepath.Join(t.TempDir(), "licenses.json")
+	if err := os.WriteFile(tmpFile, []byte(mapping), 0644); err != nil {
+		t.Fatalf("failed to write license map: %v", err)
+	}
+	if err := LoadLicenseMap(tmpFile); err != nil {
+		t.Fatalf("failed to load license map: %v", err)
+	}
+	defer LoadLicenseMap("")
+
+	match := &models.MatchResult{
+		MatchType:    "full_file",
+		ReferenceURL: "https://github.com/madler/zlib/archive/v1.3.zip",
+		ReferenceMD5: "00fffff25afaa0d78ff1c6f41ba7f965",
+	}
+	enrichLicenses("osskb-core", match)
+
+	expected := []string{"GPL-2.0-only", "Zlib"}
+	if !reflect.DeepEqual(match.Licenses, expected) {
+		t.Errorf("expected licenses %v, got %v", expected, match.Licenses)
+	}
+	if !match.Copyleft {
+		t.Error("expected copyleft flag to be set")
+	}
+}
@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Default limits of local comparisons
+const (
+	DefaultMaxSubmissions   = 10 // Hashes found in more submissions are ignored as common code
+	DefaultClusterThreshold = 50 // Similarity percentage linking two submissions into a cluster
+)
+
+// CompareOptions configures local comparisons, which match fingerprints with each other
+// instead of querying the knowledge base
+type CompareOptions struct {
+	WFP            WFPOptions // Fingerprinting options, WFP.Progress receives one step per submission
+	MinHits        int        // Shared hashes required to report a pair (default: 1)
+	MaxSubmissions int        // Hashes found in more submissions are ignored (default: DefaultMaxSubmissions)
+
+	ClusterThreshold float64 // Similarity linking submissions into clusters (default: DefaultClusterThreshold)
+}
+
+// localFile is a fingerprinted file of a local comparison
+type localFile struct {
+	doc    int // Submission the file belongs to
+	path   string
+	hashes []uint32
+	lines  []uint32
+}
+
+// posting is an occurrence of a hash in a file
+type posting struct {
+	file int
+	line uint32
+}
+
+// localCorpus is an in-memory hash index of the fingerprinted submissions
+type localCorpus struct {
+	names []string
+	files []localFile
+	index map[uint32][]posting
+}
+
+func newLocalCorpus() *localCorpus {
+	return &localCorpus{index: make(map[uint32][]posting)}
+}
+
+// scanState is the package state a directory walk sets up for the scan of its files
+type scanState struct {
+	manifests    []string
+	declaredDeps map[string]*models.Dependency
+	gitHead      string
+	changedLines map[string][]models.Range
+}
+
+// saveScanState returns the current scan state
+func saveScanState() scanState {
+	return scanState{manifests: manifests, declaredDeps: declaredDeps, gitHead: gitHead, changedLines: changedLines}
+}
+
+// restore sets the scan state back
+func (s scanState) restore() {
+	manifests, declaredDeps, gitHead, changedLines = s.manifests, s.declaredDeps, s.gitHead, s.changedLines
+}
+
+// fingerprintTree generates the WFP of a file or directory and parses it with its hashes.
+// The scan state (declared dependencies, git diff) is left as it was, so that code
+// fingerprinted for comparison does not affect the scan of the target code.
+func fingerprintTree(ctx context.Context, root string, opts WFPOptions) ([]*models.WFPData, error) {
+	defer saveScanState().restore()
+	var buf bytes.Buffer
+	opts.Progress = nil
+	if err := GenerateWFP(ctx, root, &buf, opts); err != nil {
+		return nil, err
+	}
+	return readWFP(&buf, true)
+}
+
+// add indexes the files of a submission, without the hashes of the template
+func (c *localCorpus) add(name string, entries []*models.WFPData) {
+	doc := len(c.names)
+	c.names = append(c.names, name)
+	for _, entry := range entries {
+		subtractTemplate(entry)
+		file := len(c.files)
+		c.files = append(c.files, localFile{doc: doc, path: entry.FilePath, hashes: entry.Hashes, lines: entry.Lines})
+		for i, hash := range entry.Hashes {
+			c.index[hash] = append(c.index[hash], posting{file: file, line: entry.Lines[i]})
+		}
+	}
+}
+
+// CompareCohort compares the submissions of a cohort with each other.
+// Each top-level subdirectory of dir is one submission.
+func CompareCohort(ctx context.Context, dir string, opts CompareOptions) (*models.CohortReport, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cohort: %v", err)
+	}
+	var names []string
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !entry.IsDir() {
+			DebugLog("Skipping %s: not a submission directory\n", filepath.Join(dir, entry.Name()))
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	if len(names) < 2 {
+		return nil, fmt.Errorf("cohort %s needs at least two submission directories, found %d", dir, len(names))
+	}
+
+	c := newLocalCorpus()
+	for i, name := range names {
+		entries, err := fingerprintTree(ctx, filepath.Join(dir, name), opts.WFP)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			DebugLog("Submission %s: %v\n", name, err)
+			if opts.WFP.Warnings != nil {
+				fmt.Fprintf(opts.WFP.Warnings, "Warning: submission %s: %v\n", name, err)
+			}
+		}
+		c.add(name, entries)
+		if opts.WFP.Progress != nil {
+			fmt.Fprintf(opts.WFP.Progress, "progress:%d/%d\n", i+1, len(names))
+		}
+	}
+	return c.compare(opts), nil
+}
+
+// sortedHashes returns the indexed hashes in a fixed order, so that results do not depend
+// on map iteration
+func (c *localCorpus) sortedHashes() []uint32 {
+	hashes := make([]uint32, 0, len(c.index))
+	for hash := range c.index {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	return hashes
+}
+
+// docPair and filePair identify the two sides of a match
+type docPair struct{ a, b int }
+type filePair struct{ a, b int }
+
+// fileMatchLines accumulates the shared lines of a file pair
+type fileMatchLines struct {
+	hashes         int
+	lastHash       int // Index of the last hash counted, hashes are counted once per file pair
+	linesA, linesB []int
+}
+
+// pairMatches accumulates the shared hashes of a submission pair
+type pairMatches struct {
+	hashes int
+	files  map[filePair]*fileMatchLines
+}
+
+// compare reports the submission pairs sharing at least opts.MinHits hashes. Hashes found
+// in more than opts.MaxSubmissions submissions are common code and do not count.
+func (c *localCorpus) compare(opts CompareOptions) *models.CohortReport {
+	maxDocs := opts.MaxSubmissions
+	if maxDocs < 1 {
+		maxDocs = DefaultMaxSubmissions
+	}
+	minHits := opts.MinHits
+	if minHits < 1 {
+		minHits = 1
+	}
+
+	hashes := c.sortedHashes()
+	distinct := make([]int, len(c.names))
+	pairs := make(map[docPair]*pairMatches)
+	for n, hash := range hashes {
+		byDoc := make(map[int][]posting)
+		var docs []int
+		for _, p := range c.index[hash] {
+			doc := c.files[p.file].doc
+			if byDoc[doc] == nil {
+				docs = append(docs, doc)
+			}
+			byDoc[doc] = append(byDoc[doc], p)
+		}
+		if len(docs) > maxDocs {
+			continue
+		}
+		for _, doc := range docs {
+			distinct[doc]++
+		}
+		sort.Ints(docs)
+
+		for i := 0; i < len(docs); i++ {
+			for j := i + 1; j < len(docs); j++ {
+				key := docPair{docs[i], docs[j]}
+				pm := pairs[key]
+				if pm == nil {
+					pm = &pairMatches{files: make(map[filePair]*fileMatchLines)}
+					pairs[key] = pm
+				}
+				pm.hashes++
+				for _, pa := range byDoc[key.a] {
+					for _, pb := range byDoc[key.b] {
+						fp := filePair{pa.file, pb.file}
+						fm := pm.files[fp]
+						if fm == nil {
+							fm = &fileMatchLines{lastHash: -1}
+							pm.files[fp] = fm
+						}
+						if fm.lastHash != n {
+							fm.lastHash = n
+							fm.hashes++
+						}
+						fm.linesA = append(fm.linesA, int(pa.line))
+						fm.linesB = append(fm.linesB, int(pb.line))
+					}
+				}
+			}
+		}
+	}
+
+	report := &models.CohortReport{Submissions: []models.Submission{}, Pairs: []models.SimilarPair{}}
+	files := make([]int, len(c.names))
+	for _, f := range c.files {
+		files[f.doc]++
+	}
+	for doc, name := range c.names {
+		report.Submissions = append(report.Submissions, models.Submission{Name: name, Files: files[doc], Hashes: distinct[doc]})
+	}
+
+	for key, pm := range pairs {
+		if pm.hashes < minHits {
+			continue
+		}
+		pair := models.SimilarPair{
+			A:           c.names[key.a],
+			B:           c.names[key.b],
+			SimilarityA: similarity(pm.hashes, distinct[key.a]),
+			SimilarityB: similarity(pm.hashes, distinct[key.b]),
+			Hashes:      pm.hashes,
+		}
+		linesA, linesB := make(map[int][]int), make(map[int][]int)
+		for fp, fm := range pm.files {
+			pair.Matches = append(pair.Matches, models.FileMatch{
+				FileA:  c.files[fp.a].path,
+				FileB:  c.files[fp.b].path,
+				LinesA: lineRanges(fm.linesA),
+				LinesB: lineRanges(fm.linesB),
+				Hashes: fm.hashes,
+			})
+			linesA[fp.a] = append(linesA[fp.a], fm.linesA...)
+			linesB[fp.b] = append(linesB[fp.b], fm.linesB...)
+		}
+		for _, lines := range linesA {
+			pair.SharedLinesA += coveredLines(lines)
+		}
+		for _, lines := range linesB {
+			pair.SharedLinesB += coveredLines(lines)
+		}
+		sort.Slice(pair.Matches, func(i, j int) bool {
+			mi, mj := pair.Matches[i], pair.Matches[j]
+			if mi.Hashes != mj.Hashes {
+				return mi.Hashes > mj.Hashes
+			}
+			if mi.FileA != mj.FileA {
+				return mi.FileA < mj.FileA
+			}
+			return mi.FileB < mj.FileB
+		})
+		report.Pairs = append(report.Pairs, pair)
+	}
+	sort.Slice(report.Pairs, func(i, j int) bool {
+		pi, pj := report.Pairs[i], report.Pairs[j]
+		si, sj := math.Max(pi.SimilarityA, pi.SimilarityB), math.Max(pj.SimilarityA, pj.SimilarityB)
+		if si != sj {
+			return si > sj
+		}
+		if pi.Hashes != pj.Hashes {
+			return pi.Hashes > pj.Hashes
+		}
+		if pi.A != pj.A {
+			return pi.A < pj.A
+		}
+		return pi.B < pj.B
+	})
+
+	report.ClusterThreshold = opts.ClusterThreshold
+	if report.ClusterThreshold <= 0 {
+		report.ClusterThreshold = DefaultClusterThreshold
+	}
+	report.Clusters = clusterSubmissions(report)
+	return report
+}
+
+// similarity returns the percentage of total hashes that are shared
+func similarity(shared, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(math.Min(100, float64(shared)*100/float64(total))*100) / 100
+}
+
+// toRanges turns the lines of hashes into single line ranges
+func toRanges(lines []int) []models.Range {
+	ranges := make([]models.Range, len(lines))
+	for i, line := range lines {
+		ranges[i] = models.Range{From: line, To: line}
+	}
+	return ranges
+}
+
+// lineRanges formats the lines of hashes as merged ranges ("12-40,55-60")
+func lineRanges(lines []int) string {
+	formatted, _ := FormatRanges(MergeRanges(toRanges(lines), RangeMergeTolerance))
+	return formatted
+}
+
+// coveredLines counts the lines of a file covered by the lines of hashes, merged as in lineRanges
+// but without limiting the number of ranges
+func coveredLines(lines []int) int {
+	sort.Ints(lines)
+	covered := 0
+	for _, r := range mergeRangesWithTolerance(toRanges(lines), RangeMergeTolerance) {
+		covered += r.To - r.From + 1
+	}
+	return covered
+}
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// sampleCode returns a distinct C source file of the given number of functions
+func sampleCode(seed string, functions int) string {
+	var b strings.Builder
+	for i := 0; i < functions; i++ {
+		fmt.Fprintf(&b, "int %s_compute_%d(int value, int factor) {\n", seed, i)
+		fmt.Fprintf(&b, "    int result = value * factor + %d - %s_offset(value);\n", i*7, seed)
+		fmt.Fprintf(&b, "    return result > %d ? result / %d : result + factor;\n}\n\n", i+10, i+2)
+	}
+	return b.String()
+}
+
+func TestCompareCohort(t *testing.T) {
+	LoadFilters("")
+	dir := t.TempDir()
+	shared := sampleCode("shared", 8)
+	boilerplate := sampleCode("starter", 8)
+	writeFile(t, filepath.Join(dir, "alice", "main.c"), shared+sampleCode("alice", 4))
+	writeFile(t, filepath.Join(dir, "bob", "src", "solution.c"), "\n\n"+shared)
+	writeFile(t, filepath.Join(dir, "carol", "main.c"), sampleCode("carol", 10))
+	for _, name := range []string{"alice", "bob", "carol"} {
+		writeFile(t, filepath.Join(dir, name, "starter.c"), boilerplate)
+	}
+	writeFile(t, filepath.Join(dir, "README.txt"), "not a submission")
+
+	report, err := CompareCohort(context.Background(), dir, CompareOptions{MinHits: 3, MaxSubmissions: 2})
+	if err != nil {
+		t.Fatalf("CompareCohort() error = %v", err)
+	}
+	if len(report.Submissions) != 3 || report.Submissions[0].Name != "alice" || report.Submissions[1].Files != 2 {
+		t.Fatalf("unexpected submissions: %+v", report.Submissions)
+	}
+	if len(report.Pairs) != 1 {
+		t.Fatalf("expected only alice and bob to match (starter code is common), got %+v", report.Pairs)
+	}
+
+	pair := report.Pairs[0]
+	if pair.A != "alice" || pair.B != "bob" || pair.SimilarityB != 100 || pair.SimilarityA >= 100 {
+		t.Errorf("unexpected pair: %+v", pair)
+	}
+	if len(pair.Matches) != 1 {
+		t.Fatalf("expected one file match, got %+v", pair.Matches)
+	}
+	match := pair.Matches[0]
+	if pair.SharedLinesA == 0 || pair.SharedLinesB != pair.SharedLinesA {
+		t.Errorf("unexpected shared lines %d/%d", pair.SharedLinesA, pair.SharedLinesB)
+	}
+	if match.FileA != "main.c" || match.FileB != "src/solution.c" || match.Hashes != pair.Hashes {
+		t.Errorf("unexpected match: %+v", match)
+	}
+	// bob's copy starts two lines further down
+	var fromA, fromB int
+	fmt.Sscanf(match.LinesA, "%d-", &fromA)
+	fmt.Sscanf(match.LinesB, "%d-", &fromB)
+	if fromB != fromA+2 {
+		t.Errorf("lines_a %s and lines_b %s are not aligned", match.LinesA, match.LinesB)
+	}
+
+	// Without the common code limit the starter code matches everybody
+	report, _ = CompareCohort(context.Background(), dir, CompareOptions{MinHits: 3})
+	if len(report.Pairs) != 3 {
+		t.Errorf("expected 3 pairs sharing the starter code, got %d", len(report.Pairs))
+	}
+}
+
+func TestCompareCohort_TooFewSubmissions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "alice", "main.c"), sampleCode("alice", 4))
+	if _, err := CompareCohort(context.Background(), dir, CompareOptions{}); err == nil {
+		t.Errorf("CompareCohort() accepted a single submission")
+	}
+}
+
+func TestFingerprintTree_KeepsScanState(t *testing.T) {
+	LoadFilters("")
+	defer saveScanState().restore()
+	target := map[string]*models.Dependency{"npm/target": {Ecosystem: "npm", Name: "target"}}
+	declaredDeps, gitHead = target, "feature"
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.c"), sampleCode("reference", 4))
+	writeFile(t, filepath.Join(dir, "package.json"), `{"dependencies": {"reference": "1.0.0"}}`)
+	if _, err := fingerprintTree(context.Background(), dir, WFPOptions{}); err != nil {
+		t.Fatalf("fingerprintTree() error = %v", err)
+	}
+	if len(declaredDeps) != 1 || declaredDeps["npm/target"] == nil || gitHead != "feature" {
+		t.Errorf("scan state changed: %v, head %q", declaredDeps, gitHead)
+	}
+}
@@ -0,0 +1,358 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+var manifests []string
+var declaredDeps map[string]*models.Dependency
+
+var (
+	requirementPattern  = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(?:\[[^\]]*\])?\s*(?:==\s*([^\s;,]+))?`)
+	cargoSectionPattern = regexp.MustCompile(`^\[([^\]]+)\]$`)
+	cargoDepPattern     = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*(.+)$`)
+	cargoVersionPattern = regexp.MustCompile(`version\s*=\s*"([^"]*)"`)
+	cargoPackagePattern = regexp.MustCompile(`package\s*=\s*"([^"]*)"`)
+	goMajorPattern      = regexp.MustCompile(`/v[0-9]+$`)
+	pypiSeparators      = regexp.MustCompile(`[-_.]+`)
+)
+
+// isManifest reports whether a file name is a supported dependency manifest
+func isManifest(name string) bool {
+	switch name {
+	case "go.mod", "package.json", "pom.xml", "Cargo.toml":
+		return true
+	}
+	return strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt")
+}
+
+// ParseManifest returns the dependencies declared in a manifest file
+func ParseManifest(path string) ([]models.Dependency, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseManifestData(path, data)
+}
+
+// parseManifestData returns the dependencies declared in the contents of a manifest
+func parseManifestData(path string, data []byte) ([]models.Dependency, error) {
+	var deps []models.Dependency
+	var err error
+	name := filepath.Base(path)
+	switch {
+	case name == "go.mod":
+		deps = parseGoMod(data)
+	case name == "package.json":
+		deps, err = parsePackageJSON(data)
+	case name == "pom.xml":
+		deps, err = parsePomXML(data)
+	case name == "Cargo.toml":
+		deps = parseCargoToml(data)
+	default:
+		deps = parseRequirements(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range deps {
+		deps[i].Manifest = path
+	}
+	return deps, nil
+}
+
+// parseGoMod extracts the require directives of a go.mod file
+func parseGoMod(data []byte) []models.Dependency {
+	var deps []models.Dependency
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "require" {
+			if len(fields) >= 2 && fields[1] == "(" {
+				inBlock = true
+				continue
+			}
+			fields = fields[1:]
+		} else if inBlock {
+			if fields[0] == ")" {
+				inBlock = false
+				continue
+			}
+		} else {
+			continue
+		}
+
+		if len(fields) >= 2 {
+			deps = append(deps, models.Dependency{Ecosystem: "golang", Name: fields[0], Version: fields[1]})
+		}
+	}
+	return deps
+}
+
+// parsePackageJSON extracts every dependency section of a package.json file
+func parsePackageJSON(data []byte) ([]models.Dependency, error) {
+	var pkgJSON struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal(data, &pkgJSON); err != nil {
+		return nil, err
+	}
+
+	var deps []models.Dependency
+	for _, section := range []map[string]string{pkgJSON.Dependencies, pkgJSON.DevDependencies, pkgJSON.PeerDependencies, pkgJSON.OptionalDependencies} {
+		for name, version := range section {
+			deps = append(deps, models.Dependency{Ecosystem: "npm", Name: name, Version: version})
+		}
+	}
+	return deps, nil
+}
+
+// parseRequirements extracts the packages listed in a pip requirements file
+func parseRequirements(data []byte) []models.Dependency {
+	var deps []models.Dependency
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		// Skip options (-r, -e, --index-url...), URLs and local paths
+		if line == "" || strings.HasPrefix(line, "-") || strings.Contains(line, "://") ||
+			strings.HasPrefix(line, ".") || strings.HasPrefix(line, "/") {
+			continue
+		}
+		if m := requirementPattern.FindStringSubmatch(line); m != nil {
+			deps = append(deps, models.Dependency{Ecosystem: "pypi", Name: m[1], Version: m[2]})
+		}
+	}
+	return deps
+}
+
+// parsePomXML extracts the dependencies of a Maven pom.xml file
+func parsePomXML(data []byte) ([]models.Dependency, error) {
+	type pomDependency struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Version    string `xml:"version"`
+	}
+	var pom struct {
+		Dependencies []pomDependency `xml:"dependencies>dependency"`
+		Managed      []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil, err
+	}
+
+	var deps []models.Dependency
+	for _, d := range append(pom.Dependencies, pom.Managed...) {
+		version := strings.TrimSpace(d.Version)
+		// Property references can't be resolved without the full build
+		if strings.Contains(version, "${") {
+			version = ""
+		}
+		deps = append(deps, models.Dependency{
+			Ecosystem: "maven",
+			Name:      strings.TrimSpace(d.GroupID) + ":" + strings.TrimSpace(d.ArtifactID),
+			Version:   version,
+		})
+	}
+	return deps, nil
+}
+
+// parseCargoToml extracts the dependency tables of a Cargo.toml file
+func parseCargoToml(data []byte) []models.Dependency {
+	var deps []models.Dependency
+	section := ""
+	var table *models.Dependency // Dependency declared in table form: [dependencies.serde]
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if m := cargoSectionPattern.FindStringSubmatch(line); m != nil {
+			section = strings.TrimSpace(m[1])
+			table = nil
+			if i := strings.Index(section, "dependencies."); i >= 0 {
+				deps = append(deps, models.Dependency{Ecosystem: "cargo", Name: section[i+len("dependencies."):]})
+				table = &deps[len(deps)-1]
+			}
+			continue
+		}
+
+		m := cargoDepPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+
+		if table != nil {
+			switch m[1] {
+			case "version":
+				table.Version = strings.Trim(value, "\"")
+			case "package":
+				table.Name = strings.Trim(value, "\"")
+			}
+			continue
+		}
+		if !strings.HasSuffix(section, "dependencies") {
+			continue
+		}
+
+		dep := models.Dependency{Ecosystem: "cargo", Name: m[1]}
+		if strings.HasPrefix(value, "\"") {
+			dep.Version = strings.Trim(value, "\"")
+		} else {
+			if v := cargoVersionPattern.FindStringSubmatch(value); v != nil {
+				dep.Version = v[1]
+			}
+			// Renamed dependencies: alias = { package = "real-name" }
+			if p := cargoPackagePattern.FindStringSubmatch(value); p != nil {
+				dep.Name = p[1]
+			}
+		}
+		deps = append(deps, dep)
+	}
+	return deps
+}
+
+// dependencyKey returns the identity used to compare declared packages with matched components
+func dependencyKey(ecosystem, name string) string {
+	name = strings.ToLower(name)
+	switch ecosystem {
+	case "golang":
+		name = goMajorPattern.ReplaceAllString(name, "")
+		// golang.org/x modules are mirrored at github.com/golang
+		if strings.HasPrefix(name, "golang.org/x/") {
+			name = "github.com/golang/" + strings.TrimPrefix(name, "golang.org/x/")
+		}
+		// Packages in GitHub submodules belong to the owner/repo component
+		if parts := strings.Split(name, "/"); parts[0] == "github.com" && len(parts) > 3 {
+			name = strings.Join(parts[:3], "/")
+		}
+	case "pypi":
+		name = pypiSeparators.ReplaceAllString(name, "-")
+	case "cargo":
+		name = strings.ReplaceAll(name, "_", "-")
+	}
+	return ecosystem + ":" + name
+}
+
+// referenceKeys returns the dependency keys a reference URL may correspond to
+func referenceKeys(rawURL string) []string {
+	ref := parseReferenceURL(rawURL)
+	if ref == nil {
+		return nil
+	}
+
+	switch ref.Ecosystem {
+	case "github", "gitlab":
+		// A repository is a Go module, or holds the package of its name in another ecosystem
+		return []string{
+			dependencyKey("golang", ref.Ecosystem+".com/"+ref.Namespace+"/"+ref.Name),
+			dependencyKey("npm", "@"+ref.Namespace+"/"+ref.Name),
+			dependencyKey("npm", ref.Name),
+			dependencyKey("pypi", ref.Name),
+			dependencyKey("cargo", ref.Name),
+		}
+	case "golang":
+		return []string{dependencyKey("golang", ref.Namespace+"/"+ref.Name)}
+	case "npm":
+		if ref.Namespace != "" {
+			return []string{dependencyKey("npm", ref.Namespace+"/"+ref.Name)}
+		}
+		return []string{dependencyKey("npm", ref.Name)}
+	case "maven":
+		return []string{dependencyKey("maven", ref.Namespace+":"+ref.Name)}
+	}
+	return []string{dependencyKey(ref.Ecosystem, ref.Name)}
+}
+
+// loadDeclaredDependencies parses the manifests found during the directory walk
+func loadDeclaredDependencies(paths []string) {
+	declaredDeps = make(map[string]*models.Dependency)
+	for _, path := range paths {
+		deps, err := readManifest(path)
+		if err != nil {
+			DebugLog("Skipping manifest %s: %v\n", path, err)
+			continue
+		}
+		DebugLog("Manifest %s declares %d dependencies\n", path, len(deps))
+		for i := range deps {
+			key := dependencyKey(deps[i].Ecosystem, deps[i].Name)
+			if _, exists := declaredDeps[key]; !exists {
+				declaredDeps[key] = &deps[i]
+			}
+		}
+	}
+}
+
+// readManifest parses a manifest found during the directory walk, as of the head revision
+// in git diff scans
+func readManifest(path string) ([]models.Dependency, error) {
+	if gitHead == "" {
+		return ParseManifest(path)
+	}
+	relPath, err := filepath.Rel(basePath, path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readGitFile(basePath, filepath.ToSlash(relPath))
+	if err != nil {
+		return nil, err
+	}
+	return parseManifestData(path, data)
+}
+
+// annotateDeclaredDependency links a match to the declared dependency its reference URL maps to
+func annotateDeclaredDependency(match *models.MatchResult) {
+	if match == nil || len(declaredDeps) == 0 || match.ReferenceURL == "" {
+		return
+	}
+	for _, key := range referenceKeys(match.ReferenceURL) {
+		if dep, ok := declaredDeps[key]; ok {
+			match.Declared = dep
+			return
+		}
+	}
+
+	// Maven artifacts are named as their repository, whatever their group
+	if ref := parseReferenceURL(match.ReferenceURL); ref != nil && (ref.Ecosystem == "github" || ref.Ecosystem == "gitlab") {
+		var keys []string
+		for key, dep := range declaredDeps {
+			if dep.Ecosystem == "maven" && strings.HasSuffix(key, ":"+strings.ToLower(ref.Name)) {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			sort.Strings(keys)
+			match.Declared = declaredDeps[keys[0]]
+		}
+	}
+}
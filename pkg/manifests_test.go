@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		file     string
+		content  string
+		expected map[string]string // dependency name -> version
+	}{
+		{
+			file: "go.mod",
+			content: `module example.com/app
+
+go 1.22
+
+require github.com/schollz/progressbar/v3 v3.18.0
+
+require (
+	github.com/rivo/uniseg v0.4.7 // indirect
+	golang.org/x/sys v0.29.0
+)
+`,
+			expected: map[string]string{
+				"github.com/schollz/progressbar/v3": "v3.18.0",
+				"github.com/rivo/uniseg":            "v0.4.7",
+				"golang.org/x/sys":                  "v0.29.0",
+			},
+		},
+		{
+			file:     "package.json",
+			content:  `{"name": "app", "dependencies": {"lodash": "^4.17.21"}, "devDependencies": {"@babel/core": "7.0.0"}}`,
+			expected: map[string]string{"lodash": "^4.17.21", "@babel/core": "7.0.0"},
+		},
+		{
+			file:     "requirements.txt",
+			content:  "# comment\nrequests==2.31.0\nDjango>=4.0 ; python_version > '3.8'\n-r other.txt\nnumpy[extra]==1.26.0\n",
+			expected: map[string]string{"requests": "2.31.0", "Django": "", "numpy": "1.26.0"},
+		},
+		{
+			file: "pom.xml",
+			content: `<project><dependencies><dependency>
+<groupId>com.google.guava</groupId><artifactId>guava</artifactId><version>33.0.0-jre</version>
+</dependency></dependencies></project>`,
+			expected: map[string]string{"com.google.guava:guava": "33.0.0-jre"},
+		},
+		{
+			file: "Cargo.toml",
+			content: `[package]
+name = "app"
+version = "0.1.0"
+
+[dependencies]
+serde = "1.0"
+tokio = { version = "1.35", features = ["full"] }
+json = { package = "serde_json", version = "1" }
+
+[dependencies.rand]
+version = "0.8"
+`,
+			expected: map[string]string{"serde": "1.0", "tokio": "1.35", "serde_json": "1", "rand": "0.8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write manifest: %v", err)
+			}
+
+			deps, err := ParseManifest(path)
+			if err != nil {
+				t.Fatalf("failed to parse manifest: %v", err)
+			}
+			if len(deps) != len(tt.expected) {
+				t.Fatalf("expected %d dependencies, got %d: %+v", len(tt.expected), len(deps), deps)
+			}
+			for _, dep := range deps {
+				version, ok := tt.expected[dep.Name]
+				if !ok {
+					t.Errorf("unexpected dependency %s", dep.Name)
+				} else if dep.Version != version {
+					t.Errorf("expected %s version %q, got %q", dep.Name, version, dep.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestAnnotateDeclaredDependency(t *testing.T) {
+	declaredDeps = map[string]*models.Dependency{}
+	defer func() { declaredDeps = nil }()
+	for _, dep := range []models.Dependency{
+		{Ecosystem: "golang", Name: "github.com/schollz/progressbar/v3"},
+		{Ecosystem: "golang", Name: "golang.org/x/sys"},
+		{Ecosystem: "npm", Name: "@babel/core"},
+		{Ecosystem: "npm", Name: "lodash"},
+		{Ecosystem: "pypi", Name: "requests"},
+		{Ecosystem: "pypi", Name: "Django_Rest"},
+		{Ecosystem: "maven", Name: "com.google.guava:guava"},
+		{Ecosystem: "cargo", Name: "serde_json"},
+	} {
+		dep := dep
+		declaredDeps[dependencyKey(dep.Ecosystem, dep.Name)] = &dep
+	}
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://github.com/schollz/progressbar/archive/v3.18.0.zip", "github.com/schollz/progressbar/v3"},
+		{"https://github.com/golang/sys/archive/refs/tags/v0.29.0.tar.gz", "golang.org/x/sys"},
+		{"https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz", "@babel/core"},
+		{"https://files.pythonhosted.org/packages/ab/cd/django-rest-1.0.tar.gz", "Django_Rest"},
+		{"https://repo1.maven.org/maven2/com/google/guava/guava/33.0.0-jre/guava-33.0.0-jre.jar", "com.google.guava:guava"},
+		{"https://static.crates.io/crates/serde-json/serde-json-1.0.0.crate", "serde_json"},
+		{"https://github.com/madler/zlib/archive/v1.3.zip", ""},
+		{"https://github.com/lodash/lodash/archive/refs/tags/4.17.21.tar.gz", "lodash"},
+		{"https://github.com/babel/core/archive/v7.0.0.zip", "@babel/core"},
+		{"https://github.com/psf/requests/archive/v2.31.0.zip", "requests"},
+		{"https://github.com/google/guava/archive/v33.0.zip", "com.google.guava:guava"},
+		{"https://github.com/dtolnay/serde-json/archive/v1.0.0.zip", "serde_json"},
+	}
+
+	for _, tt := range tests {
+		match := &models.MatchResult{MatchType: "code_snippet", ReferenceURL: tt.url}
+		annotateDeclaredDependency(match)
+		got := ""
+		if match.Declared != nil {
+			got = match.Declared.Name
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected declared dependency %q, got %q", tt.url, tt.expected, got)
+		}
+	}
+}
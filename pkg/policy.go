@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Policy actions and verdict statuses
+const (
+	PolicyPass = "pass"
+	PolicyWarn = "warn"
+	PolicyFail = "fail"
+)
+
+// LoadPolicy reads and validates a policy file
+func LoadPolicy(fileName string) (*models.Policy, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy models.Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %v", fileName, err)
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.Action != PolicyFail && rule.Action != PolicyWarn {
+			return nil, fmt.Errorf("policy rule %d has invalid action %q (must be %q or %q)", i+1, rule.Action, PolicyFail, PolicyWarn)
+		}
+		if rule.MatchType != "" && rule.MatchType != "full_file" && rule.MatchType != "code_snippet" {
+			return nil, fmt.Errorf("policy rule %d has invalid match_type %q", i+1, rule.MatchType)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+	}
+	return &policy, nil
+}
+
+// ruleSelects reports whether a match meets all the conditions of a rule
+func ruleSelects(rule *models.PolicyRule, match *models.MatchResult) bool {
+	if match == nil || match.MatchType == "no_match" {
+		return false
+	}
+	if !rule.IncludeKnown && (match.Status == StatusIdentified || match.Declared != nil) {
+		return false
+	}
+	if rule.MatchType != "" && rule.MatchType != match.MatchType {
+		return false
+	}
+	if rule.Copyleft && !match.Copyleft {
+		return false
+	}
+	if rule.MinCoverage > 0 && match.Coverage <= rule.MinCoverage {
+		return false
+	}
+	return true
+}
+
+// EvaluatePolicy applies the policy rules to the scan results.
+// The verdict is "fail" if any fail rule is triggered, otherwise "warn" if any warn rule is triggered.
+func EvaluatePolicy(policy *models.Policy, results map[string][]*models.MatchResult) *models.PolicyVerdict {
+	verdict := &models.PolicyVerdict{Status: PolicyPass}
+
+	// Iterate files in a stable order to produce deterministic reports
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		violation := models.PolicyViolation{Rule: rule.Name, Action: rule.Action}
+		for _, file := range files {
+			selected := false
+			for _, match := range results[file] {
+				if ruleSelects(rule, match) {
+					violation.Matches++
+					selected = true
+				}
+			}
+			if selected {
+				violation.Files = append(violation.Files, file)
+			}
+		}
+
+		if violation.Matches <= rule.MaxMatches {
+			continue
+		}
+		DebugLog("Policy rule '%s' triggered by %d matches\n", rule.Name, violation.Matches)
+		verdict.Violations = append(verdict.Violations, violation)
+		if rule.Action == PolicyFail {
+			verdict.Status = PolicyFail
+		} else if verdict.Status == PolicyPass {
+			verdict.Status = PolicyWarn
+		}
+	}
+	return verdict
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestEvaluatePolicy(t *testing.T) {
+	results := map[string][]*models.MatchResult{
+		"a.c": {{MatchType: "full_file", Copyleft: true, Coverage: 100}},
+		"b.c": {{MatchType: "code_snippet", Coverage: 45}},
+		"c.c": {{MatchType: "code_snippet", Coverage: 10}},
+		"d.c": {{MatchType: "full_file", Copyleft: true, Coverage: 100, Status: StatusIdentified}},
+		"e.c": {{MatchType: "no_match"}},
+	}
+
+	tests := []struct {
+		name     string
+		rule     models.PolicyRule
+		expected string
+		files    int
+	}{
+		{
+			name:     "copyleft full file",
+			rule:     models.PolicyRule{Action: PolicyFail, MatchType: "full_file", Copyleft: true},
+			expected: PolicyFail,
+			files:    1,
+		},
+		{
+			name:     "copyleft including identified",
+			rule:     models.PolicyRule{Action: PolicyFail, MatchType: "full_file", Copyleft: true, IncludeKnown: true},
+			expected: PolicyFail,
+			files:    2,
+		},
+		{
+			name:     "coverage",
+			rule:     models.PolicyRule{Action: PolicyFail, MatchType: "code_snippet", MinCoverage: 30},
+			expected: PolicyFail,
+			files:    1,
+		},
+		{
+			name:     "snippet count below limit",
+			rule:     models.PolicyRule{Action: PolicyWarn, MatchType: "code_snippet", MaxMatches: 2},
+			expected: PolicyPass,
+		},
+		{
+			name:     "snippet count above limit",
+			rule:     models.PolicyRule{Action: PolicyWarn, MatchType: "code_snippet", MaxMatches: 1},
+			expected: PolicyWarn,
+			files:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := EvaluatePolicy(&models.Policy{Rules: []models.PolicyRule{tt.rule}}, results)
+			if verdict.Status != tt.expected {
+				t.Errorf("expected verdict %s, got %s", tt.expected, verdict.Status)
+			}
+			if tt.files > 0 && (len(verdict.Violations) != 1 || len(verdict.Violations[0].Files) != tt.files) {
+				t.Errorf("expected one violation with %d files, got %+v", tt.files, verdict.Violations)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy_InvalidAction(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(tmpFile, []byte(`{"rules": [{"name": "x", "action": "block"}]}`), 0644); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+
+	if _, err := LoadPolicy(tmpFile); err == nil {
+		t.Error("expected error for invalid action, got nil")
+	}
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// referenceInfo contains the package identity derived from a reference URL
+type referenceInfo struct {
+	Ecosystem string // golang, npm, pypi, maven, cargo, github or gitlab
+	Namespace string // Owner, scope or group (may be empty)
+	Name      string
+	Version   string
+}
+
+var (
+	// https://github.com/owner/repo/archive/1.0.zip, .../archive/refs/tags/v1.0.tar.gz
+	githubArchivePattern = regexp.MustCompile(`^https?://github\.com/([^/]+)/([^/]+)/archive/(?:refs/(?:tags|heads)/)?(.+?)(?:\.zip|\.tar\.gz|\.tgz)$`)
+	// https://github.com/owner/repo (any other GitHub link)
+	githubRepoPattern = regexp.MustCompile(`^https?://github\.com/([^/]+)/([^/#?]+)`)
+	// https://gitlab.com/group/repo/-/archive/v1.0/repo-v1.0.zip
+	gitlabArchivePattern = regexp.MustCompile(`^https?://gitlab\.com/(.+)/([^/]+)/-/archive/([^/]+)/`)
+	// https://registry.npmjs.org/@scope/name/-/name-1.0.0.tgz (the tarball is name-version)
+	npmPattern = regexp.MustCompile(`^https?://registry\.(?:npmjs\.org|yarnpkg\.com)/(?:(@[^/]+)/)?([^/]+)/-/([^/]+)\.tgz$`)
+	// https://files.pythonhosted.org/packages/.../name-1.0.tar.gz, https://pypi.org/packages/source/n/name/name-1.0.zip
+	pypiPattern = regexp.MustCompile(`^https?://(?:files\.pythonhosted\.org|pypi\.(?:python\.)?org)/packages/.*/([^/]+?)-([0-9][^/-]*)(?:\.tar\.gz|\.tar\.bz2|\.zip|-[^/]*\.whl)$`)
+	// https://repo1.maven.org/maven2/org/group/artifact/1.0/artifact-1.0.jar
+	mavenPattern = regexp.MustCompile(`^https?://(?:repo1\.maven\.org/maven2|repo\.maven\.apache\.org/maven2|central\.maven\.org/maven2)/(.+)/([^/]+)/([^/]+)/[^/]+$`)
+	// https://crates.io/api/v1/crates/name/1.0.0/download, https://static.crates.io/crates/name/name-1.0.0.crate
+	cratesAPIPattern    = regexp.MustCompile(`^https?://crates\.io/api/v1/crates/([^/]+)/([^/]+)/download$`)
+	cratesStaticPattern = regexp.MustCompile(`^https?://static\.crates\.io/crates/([^/]+)/([^/]+)\.crate$`)
+	// https://proxy.golang.org/github.com/owner/repo/@v/v1.0.0.zip
+	goProxyPattern = regexp.MustCompile(`^https?://proxy\.golang\.org/(.+)/@v/([^/]+)\.zip$`)
+)
+
+// parseReferenceURL derives the package identity from a KB reference URL.
+// It returns nil when the URL does not follow any of the known patterns.
+func parseReferenceURL(rawURL string) *referenceInfo {
+	if m := githubArchivePattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "github", Namespace: m[1], Name: m[2], Version: m[3]}
+	}
+	if m := gitlabArchivePattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "gitlab", Namespace: m[1], Name: m[2], Version: m[3]}
+	}
+	if m := npmPattern.FindStringSubmatch(rawURL); m != nil {
+		if version, ok := strings.CutPrefix(m[3], m[2]+"-"); ok && version != "" {
+			return &referenceInfo{Ecosystem: "npm", Namespace: m[1], Name: m[2], Version: version}
+		}
+	}
+	if m := pypiPattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "pypi", Name: m[1], Version: m[2]}
+	}
+	if m := mavenPattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "maven", Namespace: strings.ReplaceAll(m[1], "/", "."), Name: m[2], Version: m[3]}
+	}
+	if m := cratesAPIPattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "cargo", Name: m[1], Version: m[2]}
+	}
+	if m := cratesStaticPattern.FindStringSubmatch(rawURL); m != nil {
+		if version, ok := strings.CutPrefix(m[2], m[1]+"-"); ok && version != "" {
+			return &referenceInfo{Ecosystem: "cargo", Name: m[1], Version: version}
+		}
+	}
+	if m := goProxyPattern.FindStringSubmatch(rawURL); m != nil {
+		module := unescapeGoModulePath(m[1])
+		namespace, name := module, module
+		if i := strings.LastIndex(module, "/"); i >= 0 {
+			namespace, name = module[:i], module[i+1:]
+		}
+		return &referenceInfo{Ecosystem: "golang", Namespace: namespace, Name: name, Version: m[2]}
+	}
+	if m := githubRepoPattern.FindStringSubmatch(rawURL); m != nil {
+		return &referenceInfo{Ecosystem: "github", Namespace: m[1], Name: strings.TrimSuffix(m[2], ".git")}
+	}
+	return nil
+}
+
+// purl returns the package URL (https://github.com/package-url/purl-spec) of the reference
+func (r *referenceInfo) purl() string {
+	var b strings.Builder
+	b.WriteString("pkg:" + r.Ecosystem + "/")
+	if r.Namespace != "" {
+		// Segments are percent-encoded, npm scopes become %40scope
+		namespace := strings.ReplaceAll(url.PathEscape(r.Namespace), "%2F", "/")
+		b.WriteString(strings.ReplaceAll(namespace, "@", "%40") + "/")
+	}
+	name := r.Name
+	if r.Ecosystem == "pypi" {
+		name = pypiSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	b.WriteString(url.PathEscape(name))
+	if r.Version != "" {
+		b.WriteString("@" + url.PathEscape(r.Version))
+	}
+	return b.String()
+}
+
+// setComponentInfo fills the purl, component, vendor and version of a match from its reference URL.
+// Matches with unknown URL patterns are left unchanged.
+func setComponentInfo(match *models.MatchResult) {
+	if match == nil || match.ReferenceURL == "" {
+		return
+	}
+	ref := parseReferenceURL(match.ReferenceURL)
+	if ref == nil {
+		return
+	}
+	match.Purl = ref.purl()
+	match.Component = ref.Name
+	match.Vendor = strings.TrimPrefix(ref.Namespace, "@")
+	match.Version = ref.Version
+}
+
+// unescapeGoModulePath reverts the module proxy case encoding ("!a" stands for "A")
+func unescapeGoModulePath(p string) string {
+	if unescaped, err := url.PathUnescape(p); err == nil {
+		p = unescaped
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '!' && i+1 < len(p) {
+			i++
+			b.WriteString(strings.ToUpper(p[i : i+1]))
+			continue
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestSetComponentInfo(t *testing.T) {
+	tests := []struct {
+		url       string
+		purl      string
+		component string
+		vendor    string
+		version   string
+	}{
+		{
+			url:       "https://github.com/accelbyte/accelbyte-unreal-sdk-plugin/archive/24.3.0.zip",
+			purl:      "pkg:github/accelbyte/accelbyte-unreal-sdk-plugin@24.3.0",
+			component: "accelbyte-unreal-sdk-plugin", vendor: "accelbyte", version: "24.3.0",
+		},
+		{
+			url:       "https://github.com/madler/zlib/archive/refs/tags/v1.3.1.tar.gz",
+			purl:      "pkg:github/madler/zlib@v1.3.1",
+			component: "zlib", vendor: "madler", version: "v1.3.1",
+		},
+		{
+			url:       "https://gitlab.com/gitlab-org/gitaly/-/archive/v16.0.0/gitaly-v16.0.0.zip",
+			purl:      "pkg:gitlab/gitlab-org/gitaly@v16.0.0",
+			component: "gitaly", vendor: "gitlab-org", version: "v16.0.0",
+		},
+		{
+			url:       "https://registry.npmjs.org/@babel/core/-/core-7.23.0.tgz",
+			purl:      "pkg:npm/%40babel/core@7.23.0",
+			component: "core", vendor: "babel", version: "7.23.0",
+		},
+		{
+			url:       "https://registry.npmjs.org/@babel/core/-/core-7.23.0-beta.1.tgz",
+			purl:      "pkg:npm/%40babel/core@7.23.0-beta.1",
+			component: "core", vendor: "babel", version: "7.23.0-beta.1",
+		},
+		{
+			url:       "https://registry.npmjs.org/date-fns/-/date-fns-2.0.0-alpha.27.tgz",
+			purl:      "pkg:npm/date-fns@2.0.0-alpha.27",
+			component: "date-fns", version: "2.0.0-alpha.27",
+		},
+		{
+			url:       "https://files.pythonhosted.org/packages/9d/be/Django_Rest-3.14.0.tar.gz",
+			purl:      "pkg:pypi/django-rest@3.14.0",
+			component: "Django_Rest", version: "3.14.0",
+		},
+		{
+			url:       "https://repo1.maven.org/maven2/com/google/guava/guava/33.0.0-jre/guava-33.0.0-jre.jar",
+			purl:      "pkg:maven/com.google.guava/guava@33.0.0-jre",
+			component: "guava", vendor: "com.google.guava", version: "33.0.0-jre",
+		},
+		{
+			url:       "https://crates.io/api/v1/crates/serde/1.0.193/download",
+			purl:      "pkg:cargo/serde@1.0.193",
+			component: "serde", version: "1.0.193",
+		},
+		{
+			url:       "https://static.crates.io/crates/tokio-util/tokio-util-0.8.0-rc.1.crate",
+			purl:      "pkg:cargo/tokio-util@0.8.0-rc.1",
+			component: "tokio-util", version: "0.8.0-rc.1",
+		},
+		{
+			url:       "https://proxy.golang.org/github.com/!burnt!sushi/toml/@v/v1.3.2.zip",
+			purl:      "pkg:golang/github.com/BurntSushi/toml@v1.3.2",
+			component: "toml", vendor: "github.com/BurntSushi", version: "v1.3.2",
+		},
+		{
+			url: "https://example.com/downloads/library.zip",
+		},
+	}
+
+	for _, tt := range tests {
+		match := &models.MatchResult{MatchType: "full_file", ReferenceURL: tt.url}
+		setComponentInfo(match)
+		if match.Purl != tt.purl || match.Component != tt.component || match.Vendor != tt.vendor || match.Version != tt.version {
+			t.Errorf("%s: got purl=%q component=%q vendor=%q version=%q", tt.url, match.Purl, match.Component, match.Vendor, match.Version)
+		}
+		if match.ReferenceURL != tt.url {
+			t.Errorf("%s: reference URL should not change", tt.url)
+		}
+	}
+}
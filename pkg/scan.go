@@ -10,6 +10,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"regexp"
@@ -136,39 +137,97 @@ func FormatRanges(ranges []models.Range) (string, string) {
 	return strings.Join(parts, ","), strings.Join(oss, ",")
 }
 
-// ReadWFPFile reads WFP files and extracts data for each file
+// RangesCoverage returns the percentage of the fingerprinted lines covered by the ranges.
+// totalLines is the last line holding a hash (see models.WFPData).
+func RangesCoverage(ranges []models.Range, totalLines int) float64 {
+	if totalLines <= 0 {
+		return 0
+	}
+
+	covered := 0
+	for _, r := range ranges {
+		covered += r.To - r.From + 1
+	}
+	coverage := math.Min(100, float64(covered)*100/float64(totalLines))
+	return math.Round(coverage*100) / 100
+}
+
+// ReadWFPFile reads WFP files and extracts data for each file.
+// Besides the file= header it reads the fh2=, hpsm= and mode= sections and tracks the last
+// line holding hashes; snippet hashes are left to deps.ParseWFPFileForMD5.
 func ReadWFPFile(filename string) ([]*models.WFPData, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	return readWFP(file, false)
+}
 
+// readWFP parses a WFP stream, keeping the snippet hashes of every file if hashes is set
+func readWFP(r io.Reader, hashes bool) ([]*models.WFPData, error) {
 	var entries []*models.WFPData
+	var current *models.WFPData
 	filePattern := regexp.MustCompile(`^file=([a-f0-9]{32}),([0-9]+),(.+)$`)
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if matches := filePattern.FindStringSubmatch(line); matches != nil {
+			current = nil
 			md5Bytes, err := hex.DecodeString(matches[1])
 			if err != nil {
 				continue
 			}
 
-			totalLines, err := strconv.Atoi(matches[2])
+			size, err := strconv.Atoi(matches[2])
 			if err != nil {
 				continue
 			}
 
 			entry := &models.WFPData{
-				MD5Hex:     matches[1],
-				TotalLines: totalLines,
-				FilePath:   matches[3],
+				MD5Hex:   matches[1],
+				Size:     size,
+				FilePath: matches[3],
 			}
 			copy(entry.MD5[:], md5Bytes)
 
 			entries = append(entries, entry)
+			current = entry
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "fh2":
+			current.FH2 = value
+		case "hpsm":
+			current.HPSM = value
+		case "mode":
+			current.Mode = value
+		default:
+			// Snippet hashes: line_number=hash1,hash2,...
+			if lineNum, err := strconv.Atoi(key); err == nil {
+				if lineNum > current.TotalLines {
+					current.TotalLines = lineNum
+				}
+				if hashes {
+					for _, hashStr := range strings.Split(value, ",") {
+						if hash, err := strconv.ParseUint(hashStr, 16, 32); err == nil {
+							current.Hashes = append(current.Hashes, uint32(hash))
+							current.Lines = append(current.Lines, uint32(lineNum))
+						}
+					}
+				}
+			} else {
+				DebugLog("Ignoring unknown WFP section '%s' in %s\n", key, current.FilePath)
+			}
 		}
 	}
 
@@ -191,6 +250,11 @@ func FilterValidRanges(ranges []models.Range) []models.Range {
 // First tries full MD5 match, then snippet matching if no full match is found
 // minHits: minimum number of hits required for a valid snippet match (default: 3)
 func ProcessWFPEntry(kbName string, entry *models.WFPData, wfpFilePath string, minHits int) (*models.MatchResult, error) {
+	// Unmodified template files only hold starter code
+	if isTemplateFile(entry.MD5Hex) {
+		return nil, fmt.Errorf("file is part of the template")
+	}
+
 	// Step 1: Try full MD5 match
 	DebugLog("Step 1: Checking full MD5 match...\n")
 	records, err := GetFirstURLRecords(kbName, entry.MD5Hex)
@@ -206,6 +270,8 @@ func ProcessWFPEntry(kbName string, entry *models.WFPData, wfpFilePath string, m
 			Instances:     instances,
 			ReferenceURL:  records[1], // URL is at index 1
 			ReferenceFile: records[0], // File is at index 0
+			ReferenceMD5:  entry.MD5Hex,
+			Coverage:      100,
 		}
 		return result, nil
 	}
@@ -217,6 +283,10 @@ func ProcessWFPEntry(kbName string, entry *models.WFPData, wfpFilePath string, m
 	if err != nil {
 		return nil, fmt.Errorf("error parsing WFP file: %v", err)
 	}
+	// Only code not found in the template can produce hits
+	if subtractTemplate(wfpData) > 0 && len(wfpData.Hashes) == 0 {
+		return nil, fmt.Errorf("no hashes left after removing the template")
+	}
 
 	// Execute snippet scan
 	if wfpAvailable {
@@ -280,6 +350,8 @@ func ProcessWFPEntry(kbName string, entry *models.WFPData, wfpFilePath string, m
 			Instances:     instances,
 			ReferenceURL:  records[1],
 			ReferenceFile: records[0],
+			ReferenceMD5:  bestMatch.FileMD5Hex,
+			Coverage:      RangesCoverage(mergedRanges, wfpData.TotalLines),
 			Hits:          bestMatch.Hits,
 			Ranges:        mergedRanges,
 		}
@@ -296,6 +368,12 @@ func ScanWFPFile(kbName, wfpFilePath string, minHits int, progress io.Writer, nu
 	if err != nil {
 		return nil, fmt.Errorf("error reading WFP file: %v", err)
 	}
+	// The knowledge base holds standard fingerprints, other modes would never match
+	for _, entry := range entries {
+		if entry.Mode != ModeStandard {
+			return nil, fmt.Errorf("%s was fingerprinted in %s mode, which cannot be scanned against the knowledge base", entry.FilePath, entry.Mode)
+		}
+	}
 
 	// Initialize snippet scanner once for all files
 	wfpAvailable = deps.SnippetWrapperInit(kbName, false)
@@ -339,6 +417,12 @@ func ScanWFPFile(kbName, wfpFilePath string, minHits int, progress io.Writer, nu
 					workerID, item.index+1, len(entries), item.entry.FilePath, item.entry.MD5Hex)
 
 				match, err := ProcessWFPEntry(kbName, item.entry, wfpFilePath, minHits)
+				if err == nil {
+					setComponentInfo(match)
+					annotateDeclaredDependency(match)
+					enrichLicenses(kbName, match)
+					applyIdentifications(item.entry, match)
+				}
 
 				// Store result
 				resultsMutex.Lock()
@@ -151,3 +151,42 @@ func TestReadWFPFile(t *testing.T) {
 		t.Error("expected FilePath to be set")
 	}
 }
+
+func TestRangesCoverage(t *testing.T) {
+	ranges := []models.Range{
+		{From: 1, To: 10, Oss: 1},
+		{From: 21, To: 30, Oss: 21},
+	}
+
+	coverage := RangesCoverage(ranges, 40)
+	if coverage != 50 {
+		t.Errorf("expected coverage 50, got %v", coverage)
+	}
+
+	if RangesCoverage(ranges, 0) != 0 {
+		t.Error("expected zero coverage without lines")
+	}
+}
+
+func TestReadWFPFile_Sections(t *testing.T) {
+	entries, err := ReadWFPFile("../test/mix.wfp")
+	if err != nil {
+		t.Fatalf("failed to read WFP file: %v", err)
+	}
+
+	if len(entries) < 2 {
+		t.Fatalf("expected at least 2 entries, got %d", len(entries))
+	}
+
+	// file=001111125afaa0d78ff1c6f41ba7f965,6219,test-snippet.cpp
+	entry := entries[1]
+	if entry.Size != 6219 {
+		t.Errorf("expected size 6219, got %d", entry.Size)
+	}
+	if entry.FH2 != "cfe69038577e4a7a26c39c548249d403" {
+		t.Errorf("unexpected fh2: %s", entry.FH2)
+	}
+	if entry.TotalLines != 80 {
+		t.Errorf("expected total lines 80, got %d", entry.TotalLines)
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fileID identifies a file or directory regardless of the path used to reach it
+type fileID struct {
+	dev  uint64
+	ino  uint64
+	path string // Resolved path, on platforms without device and inode numbers
+}
+
+var followSymlinks bool
+var walkWarnings io.Writer
+var visitedDirs map[fileID]string
+var visitedFiles map[fileID]string
+var pendingLinks []string
+
+// resetSymlinkState prepares the symlink bookkeeping for a new directory walk
+func resetSymlinkState(opts WFPOptions) {
+	followSymlinks = opts.FollowSymlinks
+	walkWarnings = opts.Warnings
+	visitedDirs = make(map[fileID]string)
+	visitedFiles = make(map[fileID]string)
+	pendingLinks = nil
+}
+
+// walkSymlink handles a symbolic link found during a directory walk.
+// Links are skipped unless symlinks are followed, in which case they are queued so that
+// files are reached through their real paths first.
+func walkSymlink(path string) error {
+	if !followSymlinks {
+		DebugLog("Skipping %s: symbolic link (use --follow-symlinks to follow it)\n", path)
+		return nil
+	}
+	pendingLinks = append(pendingLinks, path)
+	return nil
+}
+
+// followPendingLinks walks the links queued during the directory walk, including the ones
+// found inside linked directories. Linked directories are walked under the link path.
+func followPendingLinks() error {
+	for len(pendingLinks) > 0 {
+		path := pendingLinks[0]
+		pendingLinks = pendingLinks[1:]
+
+		target, err := os.Stat(path)
+		if err != nil {
+			DebugLog("Skipping %s: dangling symbolic link\n", path)
+			if walkWarnings != nil {
+				fmt.Fprintf(walkWarnings, "Warning: dangling symbolic link %s\n", path)
+			}
+			continue
+		}
+		if !target.IsDir() {
+			if err := walkFunc(path, target, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			DebugLog("Skipping %s: %v\n", path, err)
+			continue
+		}
+
+		// Walk the target directory, reporting its contents under the link path
+		err = filepath.Walk(resolved, func(p string, info os.FileInfo, err error) error {
+			rel, relErr := filepath.Rel(resolved, p)
+			if relErr != nil {
+				return relErr
+			}
+			return walkFunc(filepath.Join(path, rel), info, err)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstVisit records a directory (or a file, when symlinks are followed) and reports whether
+// it is seen for the first time; otherwise it returns the path it was first reached through
+func firstVisit(visited map[fileID]string, path string, info os.FileInfo) (bool, string) {
+	id, ok := fileIDOf(path, info)
+	if !ok {
+		return true, ""
+	}
+	if first, seen := visited[id]; seen {
+		return false, first
+	}
+	visited[id] = path
+	return true, ""
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// symlinkTree creates a tree with a linked directory, a cycle, a duplicate file link and a dangling link
+func symlinkTree(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+	writeFile(t, filepath.Join(tmpDir, "project", "main.c"), source)
+	writeFile(t, filepath.Join(tmpDir, "shared", "util.c"), strings.Repeat("long twice(long v) { return v + v; }\n", 10))
+
+	links := map[string]string{
+		filepath.Join(tmpDir, "project", "shared"):   filepath.Join("..", "shared"),
+		filepath.Join(tmpDir, "project", "loop"):     ".",
+		filepath.Join(tmpDir, "project", "alias.c"):  "main.c",
+		filepath.Join(tmpDir, "project", "broken.c"): "missing.c",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symbolic links not supported: %v", err)
+		}
+	}
+	return filepath.Join(tmpDir, "project")
+}
+
+func TestGenerateWFP_Symlinks(t *testing.T) {
+	root := symlinkTree(t)
+
+	var buf, warnings bytes.Buffer
+	opts := WFPOptions{Threads: 2, FollowSymlinks: true, Warnings: &warnings}
+	if err := GenerateWFP(context.Background(), root, &buf, opts); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, ",main.c\n") || !strings.Contains(out, ",shared/util.c\n") {
+		t.Errorf("expected main.c and the linked shared/util.c, got:\n%s", out)
+	}
+	if strings.Contains(out, "alias.c") || strings.Contains(out, "loop/") {
+		t.Errorf("files reachable through several links should be fingerprinted once, got:\n%s", out)
+	}
+	if got := strings.Count(out, "file="); got != 2 {
+		t.Errorf("expected 2 files, got %d", got)
+	}
+	if !strings.Contains(warnings.String(), "dangling symbolic link") || !strings.Contains(warnings.String(), "broken.c") {
+		t.Errorf("expected a dangling link warning, got %q", warnings.String())
+	}
+}
+
+func TestGenerateWFP_SymlinksNotFollowed(t *testing.T) {
+	root := symlinkTree(t)
+
+	var buf, warnings bytes.Buffer
+	if err := GenerateWFP(context.Background(), root, &buf, WFPOptions{Warnings: &warnings}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if got := strings.Count(buf.String(), "file="); got != 1 {
+		t.Errorf("expected only main.c without following links, got:\n%s", buf.String())
+	}
+	if warnings.Len() != 0 {
+		t.Errorf("unexpected warnings: %q", warnings.String())
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+// Snippet hashes and file MD5s of the template (starter code), nil when no template is set
+var (
+	templateHashes map[uint32]bool
+	templateFiles  map[string]bool
+)
+
+// LoadTemplate fingerprints starter code shared by every copy (a file or directory) so that
+// its hashes are removed from the scanned files before matching. The template is
+// fingerprinted with the same options as the scanned code, except for the git diff ones:
+// the whole template is always removed. An empty root clears it.
+func LoadTemplate(ctx context.Context, root string, opts WFPOptions) error {
+	templateHashes, templateFiles = nil, nil
+	if root == "" {
+		return nil
+	}
+	opts.GitDiff, opts.ChangedLinesOnly = "", false
+	entries, err := fingerprintTree(ctx, root, opts)
+	if err != nil {
+		return fmt.Errorf("error fingerprinting template %s: %v", root, err)
+	}
+
+	hashes := make(map[uint32]bool)
+	files := make(map[string]bool)
+	for _, entry := range entries {
+		files[entry.MD5Hex] = true
+		for _, hash := range entry.Hashes {
+			hashes[hash] = true
+		}
+	}
+	templateHashes, templateFiles = hashes, files
+	DebugLog("Loaded template %s: %d files, %d hashes\n", root, len(files), len(hashes))
+	return nil
+}
+
+// isTemplateFile reports whether a file is an unmodified copy of a template file
+func isTemplateFile(md5Hex string) bool {
+	return templateFiles[md5Hex]
+}
+
+// subtractTemplate removes the template hashes from the snippet hashes of a file
+// and returns the number of hashes removed
+func subtractTemplate(entry *models.WFPData) int {
+	if len(templateHashes) == 0 {
+		return 0
+	}
+	kept := 0
+	for i, hash := range entry.Hashes {
+		if templateHashes[hash] {
+			continue
+		}
+		entry.Hashes[kept] = hash
+		entry.Lines[kept] = entry.Lines[i]
+		kept++
+	}
+	removed := len(entry.Hashes) - kept
+	entry.Hashes = entry.Hashes[:kept]
+	entry.Lines = entry.Lines[:kept]
+	if removed > 0 {
+		DebugLog("Removed %d template hashes from %s\n", removed, entry.FilePath)
+	}
+	return removed
+}
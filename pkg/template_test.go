@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
+)
+
+func TestSubtractTemplate(t *testing.T) {
+	defer func() { templateHashes, templateFiles = nil, nil }()
+
+	entry := &models.WFPData{FilePath: "a.c", Hashes: []uint32{1, 2, 3, 4}, Lines: []uint32{10, 11, 12, 13}}
+	if removed := subtractTemplate(entry); removed != 0 || len(entry.Hashes) != 4 {
+		t.Fatalf("no template: removed %d hashes", removed)
+	}
+
+	templateHashes = map[uint32]bool{2: true, 4: true}
+	if removed := subtractTemplate(entry); removed != 2 {
+		t.Errorf("removed %d hashes, want 2", removed)
+	}
+	if len(entry.Hashes) != 2 || entry.Hashes[0] != 1 || entry.Hashes[1] != 3 || entry.Lines[0] != 10 || entry.Lines[1] != 12 {
+		t.Errorf("unexpected hashes %v at lines %v", entry.Hashes, entry.Lines)
+	}
+}
+
+func TestLoadTemplate_Cohort(t *testing.T) {
+	LoadFilters("")
+	defer LoadTemplate(context.Background(), "", WFPOptions{})
+
+	dir := t.TempDir()
+	starter := sampleCode("starter", 8)
+	writeFile(t, filepath.Join(dir, "template", "main.c"), starter)
+	writeFile(t, filepath.Join(dir, "cohort", "alice", "main.c"), starter+sampleCode("alice", 6))
+	writeFile(t, filepath.Join(dir, "cohort", "bob", "main.c"), starter+sampleCode("bob", 6))
+	opts := CompareOptions{MinHits: 3}
+
+	report, err := CompareCohort(context.Background(), filepath.Join(dir, "cohort"), opts)
+	if err != nil || len(report.Pairs) != 1 {
+		t.Fatalf("expected the starter code to match without template: %v %+v", err, report)
+	}
+
+	if err := LoadTemplate(context.Background(), filepath.Join(dir, "template"), opts.WFP); err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	if !isTemplateFile(fileMD5(t, filepath.Join(dir, "template", "main.c"))) {
+		t.Errorf("template file MD5 not recorded")
+	}
+	report, err = CompareCohort(context.Background(), filepath.Join(dir, "cohort"), opts)
+	if err != nil {
+		t.Fatalf("CompareCohort() error = %v", err)
+	}
+	if len(report.Pairs) != 0 {
+		t.Errorf("template code still matches: %+v", report.Pairs)
+	}
+}
+
+func TestLoadTemplate_GitDiff(t *testing.T) {
+	LoadFilters("")
+	defer LoadTemplate(context.Background(), "", WFPOptions{})
+
+	repo := gitRepo(t)
+	template := filepath.Join(t.TempDir(), "scaffold")
+	writeFile(t, filepath.Join(template, "main.c"), sampleCode("starter", 8))
+	if err := LoadTemplate(context.Background(), template, WFPOptions{}); err != nil {
+		t.Fatalf("LoadTemplate() error = %v", err)
+	}
+	hashes := len(templateHashes)
+
+	// The git diff options select the scanned files, not the template ones (outside the repository)
+	opts := WFPOptions{GitDiff: "HEAD~1..HEAD", ChangedLinesOnly: true}
+	if err := LoadTemplate(context.Background(), template, opts); err != nil {
+		t.Fatalf("LoadTemplate() with --git-diff error = %v", err)
+	}
+	if len(templateHashes) != hashes {
+		t.Errorf("template has %d hashes with --git-diff, %d without", len(templateHashes), hashes)
+	}
+	var buf bytes.Buffer
+	if err := GenerateWFP(context.Background(), repo, &buf, opts); err != nil {
+		t.Fatalf("GenerateWFP() error = %v", err)
+	}
+	if strings.Count(buf.String(), "file=") != 2 {
+		t.Errorf("expected only the changed files, got:\n%s", buf.String())
+	}
+}
+
+// fileMD5 returns the MD5 recorded in the WFP of a file
+func fileMD5(t *testing.T, path string) string {
+	t.Helper()
+	entries, err := fingerprintTree(context.Background(), path, WFPOptions{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("fingerprinting %s: %v", path, err)
+	}
+	return entries[0].MD5Hex
+}
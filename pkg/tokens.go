@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"hash/crc32"
+	"path"
+	"strings"
+)
+
+// Placeholder symbols of renamed identifiers and rewritten literals
+const (
+	tokenIdentifier byte = 1
+	tokenNumber     byte = 2
+	tokenString     byte = 3
+)
+
+// Files in unsupported languages are tokenized with plain quoted strings
+var genericSyntax = &lexSyntax{quotes: []quoteSyntax{{`"`, true, false}, {`'`, true, false}}}
+
+// Keywords of the supported languages keep their own symbol, any other identifier
+// becomes tokenIdentifier
+var tokenKeywordList = []string{
+	// C family, Java, C#, JavaScript, TypeScript
+	"if", "else", "for", "while", "do", "switch", "case", "default", "break", "continue", "return",
+	"goto", "sizeof", "typedef", "struct", "union", "enum", "class", "interface", "extends",
+	"implements", "new", "delete", "this", "super", "try", "catch", "finally", "throw", "throws",
+	"static", "const", "final", "public", "private", "protected", "virtual", "override", "abstract",
+	"void", "char", "short", "int", "long", "float", "double", "signed", "unsigned", "bool",
+	"boolean", "byte", "string", "auto", "extern", "register", "volatile", "inline", "template",
+	"typename", "namespace", "using", "operator", "instanceof", "typeof", "function", "var",
+	"let", "of", "in", "async", "await", "yield", "import", "export", "from", "package",
+	"true", "false", "null", "undefined", "nil",
+	// Go
+	"func", "type", "map", "chan", "go", "defer", "select", "range", "fallthrough",
+	// Rust, Kotlin, Swift
+	"fn", "impl", "trait", "pub", "mut", "match", "loop", "val", "fun", "when", "self", "Self",
+	// Python
+	"def", "lambda", "pass", "raise", "except", "with", "as", "is", "not", "and", "or", "elif",
+	"global", "nonlocal", "assert", "del", "None", "True", "False",
+	// Shell
+	"then", "fi", "esac", "done", "local", "echo", "exit",
+}
+
+var tokenKeywords = toSet(tokenKeywordList...)
+
+// tokenPunctuation lists the printable ASCII characters outside identifiers, numbers and
+// whitespace, which keep their own symbol
+const tokenPunctuation = "!\"#%&'()*+,-./:;<=>?@[\\]^`{|}~"
+
+// firstTokenSymbol is the symbol of the first keyword, above the placeholders and newline
+const firstTokenSymbol = 16
+
+// tokenSymbols assigns every keyword and punctuation character a distinct symbol
+var tokenSymbols = func() map[string]byte {
+	symbols := make(map[string]byte, len(tokenKeywordList)+len(tokenPunctuation))
+	for _, keyword := range tokenKeywordList {
+		symbols[keyword] = byte(firstTokenSymbol + len(symbols))
+	}
+	for _, c := range tokenPunctuation {
+		symbols[string(c)] = byte(firstTokenSymbol + len(symbols))
+	}
+	return symbols
+}()
+
+// toSet builds a set of strings
+func toSet(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// tokenize turns source code (with comments already removed) into one symbol per token:
+// identifiers and literals become placeholders, keywords and punctuation keep a symbol of
+// their own. Newlines are kept so that hashes keep their line numbers.
+func tokenize(name string, text []byte) []byte {
+	syntax := lexSyntaxes[strings.ToLower(path.Ext(name))]
+	if syntax == nil {
+		syntax = genericSyntax
+	}
+
+	out := make([]byte, 0, len(text)/2)
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\n':
+			out = append(out, '\n')
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case isDigit(c):
+			j := i + 1
+			for j < len(text) && (isIdentByte(text[j]) || text[j] == '.') {
+				j++
+			}
+			out = append(out, tokenNumber)
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(text) && isIdentByte(text[j]) {
+				j++
+			}
+			if word := string(text[i:j]); tokenKeywords[word] {
+				out = append(out, symbolOf(word))
+			} else {
+				out = append(out, tokenIdentifier)
+			}
+			i = j
+		default:
+			if end := syntax.regexEnd(text, i); end > 0 {
+				out = append(out, tokenString)
+				i += end
+				continue
+			}
+			if quote, ok := syntax.quote(text[i:]); ok {
+				end := quote.literalEnd(text[i:])
+				out = append(out, tokenString)
+				out = appendNewlines(out, text[i:i+end])
+				i += end
+				continue
+			}
+			out = append(out, symbolOf(string(c)))
+			i++
+		}
+	}
+	return out
+}
+
+// symbolOf maps a keyword or punctuation character to its symbol. Other characters
+// (control characters, stray bytes) are hashed to the symbols left after the table.
+func symbolOf(token string) byte {
+	if symbol, ok := tokenSymbols[token]; ok {
+		return symbol
+	}
+	first := firstTokenSymbol + len(tokenSymbols)
+	return byte(first + int(crc32.ChecksumIEEE([]byte(token))%uint32(256-first)))
+}
+
+// tokenSymbol is the normalization of token mode: every symbol but newlines is hashed
+func tokenSymbol(b byte) byte {
+	if b == '\n' {
+		return 0
+	}
+	return b
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentByte reports whether c may be part of an identifier (non-ASCII letters included)
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z') || c >= 0x80
+}
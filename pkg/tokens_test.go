@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	a := tokenize("a.c", []byte("int total = count + 42;\nputs(\"done\");\n"))
+	b := tokenize("b.c", []byte("int  sum=n+7 ;\nputs('x');\n"))
+	if string(a) != string(b) {
+		t.Errorf("renamed code tokenized differently: %v vs %v", a, b)
+	}
+	if want := []byte{symbolOf("int"), tokenIdentifier, symbolOf("="), tokenIdentifier, symbolOf("+"), tokenNumber, symbolOf(";"), '\n'}; string(a[:len(want)]) != string(want) {
+		t.Errorf("tokenize() = %v, want prefix %v", a, want)
+	}
+	if c := tokenize("c.c", []byte("while (n) n--;\n")); string(c) == string(tokenize("c.c", []byte("if (n) n--;\n"))) {
+		t.Errorf("keywords were replaced by placeholders")
+	}
+	if multi := tokenize("a.py", []byte("x = \"\"\"a\nb\"\"\"\ny = 1\n")); strings.Count(string(multi), "\n") != 3 {
+		t.Errorf("multi-line literal lost its newlines: %v", multi)
+	}
+	// Lifetimes are not character literals, regular expressions are literals
+	if rust := tokenize("a.rs", []byte("fn f<'a>(x: &'a str) {}\n")); !strings.HasSuffix(string(rust), string([]byte{symbolOf("{"), symbolOf("}"), '\n'})) {
+		t.Errorf("lifetime swallowed the rest of the line: %v", rust)
+	}
+	if js := tokenize("a.js", []byte("x = s.replace(/'/g, y);\n")); string(js) != string(tokenize("a.js", []byte("x = s.replace(\"a\", y);\n"))) {
+		t.Errorf("regular expression not tokenized as a literal: %v", js)
+	}
+}
+
+func TestSymbolOf(t *testing.T) {
+	tokens := append([]string{}, tokenKeywordList...)
+	for _, c := range tokenPunctuation {
+		tokens = append(tokens, string(c))
+	}
+	seen := make(map[byte]string)
+	for _, token := range tokens {
+		symbol := symbolOf(token)
+		if symbol < firstTokenSymbol {
+			t.Errorf("symbolOf(%q) = %d, below the placeholders", token, symbol)
+		}
+		if other, ok := seen[symbol]; ok {
+			t.Errorf("%q and %q share the symbol %d", other, token, symbol)
+		}
+		seen[symbol] = token
+	}
+	if symbol := symbolOf("\x01"); int(symbol) < firstTokenSymbol+len(tokens) {
+		t.Errorf("unknown character mapped to the table symbol %d", symbol)
+	}
+}
+
+func TestFingerprintData_Tokens(t *testing.T) {
+	original := `def average_score(students):
+    total = 0
+    for student in students:
+        total += student.score * weight_for(student.level)
+    if len(students) == 0:
+        return None
+    return total / len(students)
+
+def best_student(students):
+    best = None
+    for student in students:
+        if best is None or student.score > best.score:
+            best = student
+    return best
+`
+	renamed := strings.NewReplacer("average_score", "mean_grade", "students", "pupils", "student", "p",
+		"total", "acc", "score", "grade", "weight_for", "factor", "level", "year",
+		"best_student", "top", "best", "winner").Replace(original)
+
+	hashes := func(wfp string) string {
+		var lines []string
+		for _, line := range strings.Split(wfp, "\n") {
+			if hashLinePrefix.MatchString(line) {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	tokens := WFPOptions{Mode: ModeTokens}
+	a := fingerprintData("a.py", []byte(original), tokens)
+	b := fingerprintData("b.py", []byte(renamed), tokens)
+	if !strings.Contains(a, "\nmode="+ModeTokens+"\n") {
+		t.Errorf("WFP has no mode line:\n%s", a)
+	}
+	if hashes(a) == "" || hashes(a) != hashes(b) {
+		t.Errorf("renamed identifiers changed the token hashes:\n%s\n---\n%s", a, b)
+	}
+	if hashes(fingerprintData("a.py", []byte(original), WFPOptions{})) == hashes(fingerprintData("b.py", []byte(renamed), WFPOptions{})) {
+		t.Errorf("standard mode unexpectedly matched the renamed code")
+	}
+}
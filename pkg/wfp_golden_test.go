@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+// SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+//
+// SPDX-License-Identifier:GPL-2.0-only
+
+package pkg
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/deps"
+)
+
+// goldenDir holds source files and their reference WFPs (<name>.wfp), written by
+// test/golden/wfp.py, a standalone port of the scanoss-py winnowing that shares no code with
+// the generator. Add a source with: cd test/golden && python3 wfp.py <name>
+const goldenDir = "../test/golden"
+
+// goldenSources returns the source files with a reference WFP in the golden directory
+func goldenSources(t *testing.T) []string {
+	entries, err := os.ReadDir(goldenDir)
+	if err != nil {
+		t.Fatalf("failed to read golden directory: %v", err)
+	}
+	var sources []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, err := os.Stat(filepath.Join(goldenDir, name+".wfp")); err == nil && !entry.IsDir() {
+			sources = append(sources, name)
+		}
+	}
+	if len(sources) == 0 {
+		t.Fatal("no golden sources found")
+	}
+	return sources
+}
+
+func TestFingerprintGolden(t *testing.T) {
+	for _, name := range goldenSources(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(goldenDir, name))
+			if err != nil {
+				t.Fatalf("failed to read source: %v", err)
+			}
+			expected, err := os.ReadFile(filepath.Join(goldenDir, name+".wfp"))
+			if err != nil {
+				t.Fatalf("failed to read reference WFP: %v", err)
+			}
+
+			got := strings.Split(fingerprintData(name, data, WFPOptions{HPSM: true}), "\n")
+			want := strings.Split(string(expected), "\n")
+			for i := 0; i < len(got) || i < len(want); i++ {
+				var gotLine, wantLine string
+				if i < len(got) {
+					gotLine = got[i]
+				}
+				if i < len(want) {
+					wantLine = want[i]
+				}
+				if gotLine != wantLine {
+					t.Errorf("line %d: got %q, reference %q", i+1, gotLine, wantLine)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGoldenWFP(t *testing.T) {
+	for _, name := range goldenSources(t) {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(goldenDir, name))
+			if err != nil {
+				t.Fatalf("failed to read source: %v", err)
+			}
+			goldenFile := filepath.Join(goldenDir, name+".wfp")
+
+			entries, err := ReadWFPFile(goldenFile)
+			if err != nil {
+				t.Fatalf("failed to read WFP file: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected 1 entry, got %d", len(entries))
+			}
+			entry := entries[0]
+			if entry.Size != len(data) {
+				t.Errorf("expected size %d, got %d", len(data), entry.Size)
+			}
+			if entry.FH2 == "" || entry.HPSM == "" {
+				t.Error("expected fh2 and hpsm sections to be parsed")
+			}
+			// One hpsm hash (2 hex digits) per line ending
+			if len(entry.HPSM) > 2*strings.Count(string(data), "\n") {
+				t.Errorf("hpsm has more hashes than lines: %s", entry.HPSM)
+			}
+
+			wfpData, err := deps.ParseWFPFileForMD5(goldenFile, entry.MD5Hex)
+			if err != nil {
+				t.Fatalf("failed to parse WFP hashes: %v", err)
+			}
+			if wfpData.TotalLines != entry.TotalLines || wfpData.TotalLines == 0 {
+				t.Errorf("expected total lines %d, got %d", entry.TotalLines, wfpData.TotalLines)
+			}
+			if wfpData.Size != entry.Size || wfpData.FH2 != entry.FH2 || wfpData.HPSM != entry.HPSM {
+				t.Error("parsers disagree on the file sections")
+			}
+			if len(wfpData.Hashes) == 0 || int(wfpData.Lines[len(wfpData.Lines)-1]) != wfpData.TotalLines {
+				t.Error("expected hashes up to the last line")
+			}
+		})
+	}
+}
+
+func TestLineEndingsHash(t *testing.T) {
+	lf := []byte("int a;\nint b;\n")
+	crlf := []byte("int a;\r\nint b;\r\n")
+
+	if lineEndingsHash(lf, encodingUTF8) != md5Hex(crlf) {
+		t.Error("fh2 of an LF file should be the MD5 of its CRLF version")
+	}
+	if lineEndingsHash(crlf, encodingUTF8) != md5Hex(lf) {
+		t.Error("fh2 of a CRLF file should be the MD5 of its LF version")
+	}
+	if lineEndingsHash([]byte("no line endings"), encodingUTF8) != "" {
+		t.Error("files without line endings should have no fh2")
+	}
+}
+
+func md5Hex(data []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(data))
+}
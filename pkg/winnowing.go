@@ -6,20 +6,32 @@
 package pkg
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Software-Transparency-Foundation/stf-plagicheck/models"
 )
 
 const GRAM = 30
 const BUFFER_RATE = 4
 const WINDOW = 64
 
+// Token mode gram and window, counted in tokens
+const TOKEN_GRAM = 12
+const TOKEN_WINDOW = 24
+
 var SKIP_SNIPPET_EXT = []string{
 	// Executables and binaries
 	".exe", ".bin", ".app", ".out", ".o", ".a", ".so", ".obj", ".dll", ".lib", ".dylib",
@@ -92,32 +104,190 @@ func intToByte(n uint32) []byte {
 
 }
 
-func LoadFilters(fileName string) {
-	bannedFiles = make(map[string]bool)
-	onlyMD5 = make(map[string]bool)
-	for r := range SKIP_SNIPPET_EXT {
-		onlyMD5[SKIP_SNIPPET_EXT[r]] = true
+func SkipFile(fileName string) bool {
+	return false
+}
+
+// lineEndingsHash returns the MD5 of the contents with the opposite line endings (fh2):
+// LF for files using CRLF and CRLF otherwise. UTF-16 contents are converted code unit by
+// code unit, in their own byte order and keeping the BOM. Files without line endings have
+// no fh2.
+func lineEndingsHash(f []byte, encoding string) string {
+	switch encoding {
+	case encodingUTF16LE:
+		return utf16LineEndingsHash(f, binary.LittleEndian)
+	case encodingUTF16BE:
+		return utf16LineEndingsHash(f, binary.BigEndian)
 	}
-	for r := range FILTERED_EXT {
-		bannedFiles[FILTERED_EXT[r]] = true
+	if !bytes.ContainsAny(f, "\r\n") {
+		return ""
+	}
+	hasCRLF := bytes.Contains(f, []byte("\r\n"))
+	normalized := bytes.ReplaceAll(f, []byte("\r\n"), []byte("\n"))
+	normalized = bytes.ReplaceAll(normalized, []byte("\r"), []byte("\n"))
+	if !hasCRLF {
+		normalized = bytes.ReplaceAll(normalized, []byte("\n"), []byte("\r\n"))
+	}
+	return fmt.Sprintf("%x", md5.Sum(normalized))
+}
 
+// utf16LineEndingsHash is lineEndingsHash for UTF-16 contents: CR and LF are matched as
+// whole code units, never across two of them. A trailing odd byte is kept as is.
+func utf16LineEndingsHash(f []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(f)/2)
+	hasEOL, hasCRLF := false, false
+	for i := range units {
+		units[i] = order.Uint16(f[2*i:])
+		if units[i] == '\r' || units[i] == '\n' {
+			hasEOL = true
+			if i > 0 && units[i-1] == '\r' && units[i] == '\n' {
+				hasCRLF = true
+			}
+		}
+	}
+	if !hasEOL {
+		return ""
 	}
 
+	swapped := make([]byte, 0, len(f)+len(f)/8)
+	var unit [2]byte
+	appendUnit := func(u uint16) {
+		order.PutUint16(unit[:], u)
+		swapped = append(swapped, unit[:]...)
+	}
+	for i := 0; i < len(units); i++ {
+		if units[i] != '\r' && units[i] != '\n' {
+			appendUnit(units[i])
+			continue
+		}
+		if units[i] == '\r' && i+1 < len(units) && units[i+1] == '\n' {
+			i++
+		}
+		if !hasCRLF {
+			appendUnit('\r')
+		}
+		appendUnit('\n')
+	}
+	swapped = append(swapped, f[2*len(units):]...)
+	return fmt.Sprintf("%x", md5.Sum(swapped))
 }
 
-func SkipFile(fileName string) bool {
-	return false
+// crc8MaximTable is the lookup table of the CRC-8/MAXIM-DOW (reflected polynomial 0x8C) checksum
+var crc8MaximTable = func() [256]byte {
+	var table [256]byte
+	for i := range table {
+		crc := byte(i)
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x01 != 0 {
+				crc = (crc >> 1) ^ 0x8C
+			} else {
+				crc >>= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// hpsmHash returns the High Precision Snippet Matching line hashes: one CRC-8 per line
+// of the normalized contents, 0xff for empty lines and 0x00 for lines without code
+func hpsmHash(f []byte) string {
+	var result strings.Builder
+	var crc byte
+	normalizedBytes := 0
+	lastLine := 0
+	for i := 0; i < len(f); i++ {
+		if f[i] == '\n' {
+			switch {
+			case normalizedBytes > 0:
+				fmt.Fprintf(&result, "%02x", crc)
+			case lastLine+1 == i:
+				result.WriteString("ff")
+			case i-lastLine > 1:
+				result.WriteString("00")
+			}
+			crc = 0
+			normalizedBytes = 0
+			lastLine = i
+			continue
+		}
+		if b := normalize(f[i]); b != 0 {
+			crc = crc8MaximTable[b^crc]
+			normalizedBytes++
+		}
+	}
+	return result.String()
 }
-func fingerprint(filePath string) string {
-	var newByte byte
-	var window []byte
-	//var lineArrays []int
-	result := ""
+
+// fingerprint generates the WFP of a file, recorded under wfpPath
+func fingerprint(filePath, wfpPath string, opts WFPOptions) string {
 	f, err := os.ReadFile(filePath)
 	if err != nil {
 		//	fmt.Println("Could not open the file")
 		return ""
 	}
+	text, encoding := decodeText(f)
+	if reason := contentSkipReason(text, opts); reason != "" {
+		DebugLog("Skipping %s: %s\n", filePath, reason)
+		return ""
+	}
+	return fingerprintText(wfpPath, f, text, encoding, opts)
+}
+
+// fingerprintPath generates the WFP of a file found under root, or of the members of an archive
+func fingerprintPath(root, filePath string, opts WFPOptions) string {
+	if gitHead != "" {
+		return fingerprintGitFile(root, filePath, opts)
+	}
+	wfpPath := opts.wfpPath(root, filePath)
+	if opts.Archives && archiveKindOf(filePath) != archiveNone {
+		relPath, err := filepath.Rel(root, filePath)
+		if err != nil {
+			relPath = filePath
+		}
+		return fingerprintArchive(filePath, filepath.ToSlash(relPath), wfpPath, opts)
+	}
+	return fingerprint(filePath, wfpPath, opts)
+}
+
+// wfpPath returns the path recorded in the WFP for a file found under root:
+// relative to root (the file name when root is the file itself) with forward slashes
+// and the optional prefix, or absolute if requested
+func (opts WFPOptions) wfpPath(root, filePath string) string {
+	if opts.AbsolutePaths {
+		if abs, err := filepath.Abs(filePath); err == nil {
+			return filepath.ToSlash(abs)
+		}
+		return filepath.ToSlash(filePath)
+	}
+
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == "." {
+		rel = filepath.Base(filePath)
+	}
+	rel = filepath.ToSlash(rel)
+	if prefix := strings.Trim(filepath.ToSlash(opts.PathPrefix), "/"); prefix != "" {
+		rel = prefix + "/" + rel
+	}
+	return rel
+}
+
+// fingerprintData generates the WFP of the contents f, recorded under filePath
+func fingerprintData(filePath string, f []byte, opts WFPOptions) string {
+	text, encoding := decodeText(f)
+	return fingerprintText(filePath, f, text, encoding, opts)
+}
+
+// fingerprintText generates the WFP of the contents f, recorded under filePath, given
+// their text as decoded by decodeText. The file= header holds the MD5 and the size in
+// bytes of the contents, followed by the fh2= line endings hash and, if enabled, the hpsm=
+// line hashes. Winnowing works on the UTF-8 text, so that copies in other encodings
+// produce the same hashes.
+func fingerprintText(filePath string, f, text []byte, encoding string, opts WFPOptions) string {
+	var newByte byte
+	var window []byte
+	//var lineArrays []int
+	var result strings.Builder
 
 	// Limit line length to 1KB to avoid "token too long" errors
 	fileLine := fmt.Sprintf("file=%x,%d,%s\n", md5.Sum(f), len(f), filePath)
@@ -129,7 +299,33 @@ func fingerprint(filePath string) string {
 		}
 		fileLine = fmt.Sprintf("file=%x,%d,%s\n", md5.Sum(f), len(f), filePath)
 	}
-	result += fileLine
+	result.WriteString(fileLine)
+
+	// The file= MD5 and fh2 identify the original bytes, hashes are computed over UTF-8 text
+	if encoding != encodingUTF8 {
+		DebugLog("Transcoding %s from %s\n", filePath, encoding)
+	}
+	if fh2 := lineEndingsHash(f, encoding); fh2 != "" {
+		result.WriteString("fh2=" + fh2 + "\n")
+	}
+	f = text
+	if opts.Mode != ModeStandard {
+		result.WriteString("mode=" + opts.Mode + "\n")
+		f = stripCode(filePath, f, opts.Mode == ModeStripLiterals)
+	}
+
+	if opts.HPSM {
+		if hpsm := hpsmHash(f); hpsm != "" {
+			result.WriteString("hpsm=" + hpsm + "\n")
+		}
+	}
+
+	// Token mode winnows one symbol per token instead of normalized characters
+	gram, windowSize, norm := GRAM, WINDOW, normalize
+	if opts.Mode == ModeTokens {
+		f = tokenize(filePath, f)
+		gram, windowSize, norm = TOKEN_GRAM, TOKEN_WINDOW, tokenSymbol
+	}
 	lines := 1
 	//counts := 0
 	//windowPrt := 0
@@ -143,27 +339,27 @@ func fingerprint(filePath string) string {
 		if f[i] == '\n' {
 			lines++
 		}
-		newByte = normalize(f[i])
+		newByte = norm(f[i])
 		if newByte == 0 {
 			continue
 		}
 
 		window = append(window, newByte)
-		if len(window) >= GRAM {
+		if len(window) >= gram {
 
 			hashes = append(hashes, crc32.Checksum(window, crc32q))
-			if len(hashes) >= WINDOW {
+			if len(hashes) >= windowSize {
 				a := minHash(hashes)
 				if a != last {
 					last = a
 					wfp[lines] = append(wfp[lines], crc32.Checksum(intToByte(a), crc32q))
 				}
-				hashes = hashes[1:WINDOW]
+				hashes = hashes[1:windowSize]
 
 			} else {
 				//	fmt.Println("Not enoungh hashes")
 			}
-			window = window[1:GRAM]
+			window = window[1:gram]
 		} else {
 			//fmt.Println("Filling Gram", string(window))
 		}
@@ -191,58 +387,147 @@ func fingerprint(filePath string) string {
 			if len(hashLine)+len(hashStr) > 1024 {
 				// Finish current line and start a new one with same line number
 				hashLine += "\n"
-				result += hashLine
+				result.WriteString(hashLine)
 				hashLine = fmt.Sprintf("%d=", k)
 			}
 			hashLine += hashStr
 		}
-		result += hashLine
+		result.WriteString(hashLine)
 	}
-	return result
+	return result.String()
 }
 
 var wfps []string
-var progressWriter io.Writer
+var wfpsSize int64
 
 func walkFunc(path string, info os.FileInfo, err error) error {
+	if err != nil {
+		DebugLog("Skipping %s: %v\n", path, err)
+		return nil
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return walkSymlink(path)
+	}
+
+	relPath, relErr := filepath.Rel(basePath, path)
+	if relErr != nil {
+		relPath = path
+	}
+	relPath = filepath.ToSlash(relPath)
+
 	if info.IsDir() {
 		// Skip hidden directories
-		if len(info.Name()) > 0 && info.Name()[0] == '.' {
+		if relPath != "." && len(info.Name()) > 0 && info.Name()[0] == '.' {
+			DebugLog("Skipping %s: hidden directory\n", path)
 			return filepath.SkipDir
 		}
+
+		if ignores != nil {
+			if ignores.ignored(relPath, true) {
+				return filepath.SkipDir
+			}
+			// Rules of nested ignore files apply to the directory contents
+			if relPath == "." {
+				relPath = ""
+			}
+			ignores.loadDir(path, relPath)
+		}
+
+		if relPath != "" && relPath != "." {
+			if pattern := excludedPath(relPath); pattern != "" {
+				DebugLog("Skipping %s: excluded by %s\n", path, pattern)
+				return filepath.SkipDir
+			}
+		}
+
+		// Linked directories may lead back to a directory already walked
+		if followSymlinks {
+			if first, firstPath := firstVisit(visitedDirs, path, info); !first {
+				DebugLog("Skipping %s: already walked as %s (symlink cycle)\n", path, firstPath)
+				return filepath.SkipDir
+			}
+		}
 	} else {
 		// Skip hidden files (files starting with .)
 		if len(info.Name()) > 0 && info.Name()[0] == '.' {
+			DebugLog("Skipping %s: hidden file\n", path)
+			return nil
+		}
+
+		if ignores.ignored(relPath, false) {
 			return nil
 		}
 
-		if info.Size() > 100 {
-			ext1 := filepath.Ext(path)
-			baseName := filepath.Base(path)
+		// Collect dependency manifests to reconcile matches with declared packages
+		if isManifest(info.Name()) && excludedPath(relPath) == "" {
+			manifests = append(manifests, path)
+		}
 
-			// Check for minimized files (.min.js, .min.css, etc.)
-			// Pattern: filename.min.ext
-			if len(baseName) > 4 {
-				nameWithoutExt := baseName[:len(baseName)-len(ext1)]
-				if len(nameWithoutExt) >= 4 && nameWithoutExt[len(nameWithoutExt)-4:] == ".min" {
-					DebugLog("Skipping minimized file: %s\n", path)
-					return nil
-				}
+		if scanArchives && archiveKindOf(info.Name()) != archiveNone {
+			// Archive members are filtered individually when the archive is fingerprinted
+			if pattern := excludedPath(relPath); pattern != "" {
+				DebugLog("Skipping %s: excluded by %s\n", path, pattern)
+				return nil
+			}
+		} else {
+			if reason := fileSkipReason(relPath, info.Size()); reason != "" {
+				DebugLog("Skipping %s: %s\n", path, reason)
+				return nil
 			}
+			if isMinified(info.Name()) {
+				DebugLog("Skipping minimized file: %s\n", path)
+				return nil
+			}
+		}
 
-			_, banned := onlyMD5[ext1]
-			_, skipped := bannedFiles[ext1]
-			if !skipped && !banned {
-				wfps = append(wfps, path)
+		// Files reachable through several links are fingerprinted once
+		if followSymlinks {
+			if first, firstPath := firstVisit(visitedFiles, path, info); !first {
+				DebugLog("Skipping %s: same file as %s\n", path, firstPath)
+				return nil
 			}
 		}
+
+		wfps = append(wfps, path)
+		wfpsSize += info.Size()
 	}
 	return nil
 }
 
+// isMinified reports whether a file name follows the minimized file pattern (.min.js, .min.css, etc.)
+func isMinified(baseName string) bool {
+	nameWithoutExt := strings.TrimSuffix(baseName, path.Ext(baseName))
+	return strings.HasSuffix(nameWithoutExt, ".min")
+}
+
+// GenerateWFPFromData generates the WFP of in-memory contents, such as a snippet read from stdin,
+// recorded under a virtual name. Size and extension filters do not apply; line numbers are
+// relative to the start of data.
+func GenerateWFPFromData(name string, data []byte, opts WFPOptions) (string, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "", fmt.Errorf("no input to fingerprint")
+	}
+
+	wfp := fingerprintData(name, data, opts)
+	for _, line := range strings.Split(wfp, "\n") {
+		if len(line) > 0 && line[0] >= '0' && line[0] <= '9' {
+			return wfp, nil
+		}
+	}
+	return "", fmt.Errorf("input too short to generate snippet fingerprints")
+}
+
 // GenerateWFPFromFile generates WFP for a single file
 func GenerateWFPFromFile(filePath string) (string, error) {
-	LoadFilters("")
+	return generateFileWFP(filePath, WFPOptions{})
+}
+
+// generateFileWFP checks that a single file can be fingerprinted and generates its WFP
+func generateFileWFP(filePath string, opts WFPOptions) (string, error) {
+	if bannedFiles == nil {
+		LoadFilters("")
+	}
 
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
@@ -253,23 +538,23 @@ func GenerateWFPFromFile(filePath string) (string, error) {
 		return "", fmt.Errorf("path is a directory, use GenerateWFPFromDirectory instead")
 	}
 
-	if fileInfo.Size() <= 100 {
-		return "", fmt.Errorf("file too small (must be > 100 bytes)")
-	}
-
-	ext := filepath.Ext(filePath)
-	_, banned := onlyMD5[ext]
-	_, skipped := bannedFiles[ext]
-
-	if skipped {
-		return "", fmt.Errorf("file extension %s is in skip list", ext)
-	}
-
-	if banned {
-		return "", fmt.Errorf("file extension %s is banned", ext)
+	var wfp string
+	if opts.Archives && archiveKindOf(filePath) != archiveNone {
+		wfp = fingerprintArchive(filePath, filepath.Base(filePath), opts.wfpPath(filePath, filePath), opts)
+	} else {
+		if reason := fileSkipReason(filepath.Base(filePath), fileInfo.Size()); reason != "" {
+			return "", fmt.Errorf("%s", reason)
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("error reading file: %v", err)
+		}
+		text, encoding := decodeText(data)
+		if reason := contentSkipReason(text, opts); reason != "" {
+			return "", fmt.Errorf("%s", reason)
+		}
+		wfp = fingerprintText(opts.wfpPath(filePath, filePath), data, text, encoding, opts)
 	}
-
-	wfp := fingerprint(filePath)
 	if wfp == "" {
 		return "", fmt.Errorf("failed to generate fingerprint")
 	}
@@ -282,11 +567,10 @@ func GenerateWFPFromDirectory(dirPath string) (string, error) {
 	return GenerateWFPFromDirectoryWithProgress(dirPath, nil)
 }
 
-// GenerateWFPFromDirectoryWithProgress generates WFP for all files in a directory with progress reporting
+// GenerateWFPFromDirectoryWithProgress generates WFP for all files in a directory with progress reporting.
+// Files are fingerprinted one at a time and the whole WFP is kept in memory, use GenerateWFP to
+// fingerprint with several threads and stream the WFP of large trees.
 func GenerateWFPFromDirectoryWithProgress(dirPath string, progress io.Writer) (string, error) {
-	progressWriter = progress
-	LoadFilters("")
-
 	fileInfo, err := os.Stat(dirPath)
 	if err != nil {
 		return "", fmt.Errorf("error accessing directory: %v", err)
@@ -296,35 +580,224 @@ func GenerateWFPFromDirectoryWithProgress(dirPath string, progress io.Writer) (s
 		return "", fmt.Errorf("path is not a directory, use GenerateWFPFromFile instead")
 	}
 
+	var result strings.Builder
+	err = GenerateWFP(context.Background(), dirPath, &result, WFPOptions{Threads: 1, Progress: progress})
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// WFPOptions configures WFP generation
+type WFPOptions struct {
+	Threads  int       // Number of parallel fingerprinting workers (default: 1)
+	Progress io.Writer // Receives "progress:N/M" messages (optional)
+	HPSM     bool      // Emit hpsm= line hashes for High Precision Snippet Matching
+	NoIgnore bool      // Do not honour .gitignore and .plagicheckignore files
+
+	// Paths are recorded relative to the scanned directory unless AbsolutePaths is set
+	PathPrefix    string // Logical prefix prepended to relative paths (e.g. the project name)
+	AbsolutePaths bool   // Record absolute paths instead of relative ones
+
+	FollowSymlinks bool      // Walk linked directories and fingerprint linked files (skipped otherwise)
+	Warnings       io.Writer // Receives warnings such as dangling symbolic links (optional)
+
+	Archives       bool  // Fingerprint the members of zip and tar archives instead of skipping them
+	ArchiveDepth   int   // Maximum nesting of archives within archives (default: DefaultArchiveDepth)
+	ArchiveMaxSize int64 // Maximum uncompressed bytes read from an archive (default: DefaultArchiveMaxSize)
+
+	IncludeGenerated bool // Fingerprint files marked as generated code (skipped by default)
+
+	GitDiff          string // Only fingerprint the files changed in a <base>..<head> revision range of the repository at root
+	ChangedLinesOnly bool   // With GitDiff, only keep the hashes of the changed lines
+
+	Mode string // Normalization mode (ModeStandard keeps the fingerprints compatible with the KB)
+}
+
+// GenerateWFP generates the WFP of a file or of all files in a directory and streams it to w.
+// Files are fingerprinted by opts.Threads workers and written in directory walk order as soon as
+// they are ready, so only a bounded number of file blocks are held in memory.
+func GenerateWFP(ctx context.Context, root string, w io.Writer, opts WFPOptions) error {
+	fileInfo, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("error accessing path: %v", err)
+	}
+
+	if !fileInfo.IsDir() {
+		if opts.GitDiff != "" {
+			return fmt.Errorf("git diff scans require a directory of the repository")
+		}
+		wfp, err := generateFileWFP(root, opts)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, wfp)
+		return err
+	}
+
+	// A symlinked root is always walked, paths are relative to it anyway
+	if linkInfo, err := os.Lstat(root); err == nil && linkInfo.Mode()&os.ModeSymlink != 0 {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+	}
+
+	if bannedFiles == nil {
+		LoadFilters("")
+	}
+
 	// Reset global variables
 	wfps = []string{}
-	basePath = dirPath
+	wfpsSize = 0
+	manifests = []string{}
+	basePath = root
+	resetSymlinkState(opts)
+	scanArchives = opts.Archives
+	ignores = nil
+	if !opts.NoIgnore {
+		ignores = &ignoreMatcher{}
+	}
 
-	// Walk the directory
-	err = filepath.Walk(dirPath, walkFunc)
-	if err != nil {
-		return "", fmt.Errorf("error walking directory: %v", err)
+	gitHead = ""
+	changedLines = make(map[string][]models.Range)
+	if opts.GitDiff != "" {
+		// Only the files changed in the revision range are fingerprinted
+		if err := listGitDiff(root, opts); err != nil {
+			return err
+		}
+	} else {
+		// Walk the directory
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return walkFunc(path, info, err)
+		})
+		if err == nil {
+			err = followPendingLinks()
+		}
+		if err != nil {
+			return fmt.Errorf("error walking directory: %v", err)
+		}
 	}
 
+	loadDeclaredDependencies(manifests)
+
+	// A diff without changed source files is not an error, the WFP is just empty
+	if len(wfps) == 0 && opts.GitDiff != "" {
+		DebugLog("No changed files to fingerprint in %s\n", opts.GitDiff)
+		return nil
+	}
 	if len(wfps) == 0 {
-		return "", fmt.Errorf("no valid files found in directory")
+		return fmt.Errorf("no valid files found in directory")
 	}
 
-	// Generate WFP for all collected files
-	var result string
-	for i, filePath := range wfps {
-		wfp := fingerprint(filePath)
-		if wfp != "" {
-			result += wfp
+	// Ensure at least 1 thread
+	numThreads := opts.Threads
+	if numThreads < 1 {
+		numThreads = 1
+	}
+
+	DebugLog("Fingerprinting %d files with %d threads\n", len(wfps), numThreads)
+	start := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type fingerprintResult struct {
+		index int
+		wfp   string
+	}
+
+	// Slots bound the files dispatched but not yet written, which limits memory usage
+	// when a slow file holds back the output of the following ones
+	slots := make(chan struct{}, numThreads*4)
+	workChan := make(chan int)
+	resultChan := make(chan fingerprintResult, numThreads)
+
+	// Dispatch files in walk order
+	go func() {
+		defer close(workChan)
+		for i := range wfps {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case workChan <- i:
+			case <-ctx.Done():
+				return
+			}
 		}
-		if progressWriter != nil {
-			fmt.Fprintf(progressWriter, "progress:%d/%d\n", i+1, len(wfps))
+	}()
+
+	// Start worker goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < numThreads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range workChan {
+				wfp := ""
+				if ctx.Err() == nil {
+					wfp = fingerprintPath(root, wfps[index], opts)
+				}
+				resultChan <- fingerprintResult{index: index, wfp: wfp}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Write blocks in order, keeping the ones that complete early until their turn
+	pending := make(map[int]string)
+	next := 0
+	var written int64
+	var writeErr error
+	for result := range resultChan {
+		pending[result.index] = result.wfp
+		for {
+			wfp, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			<-slots
+
+			if writeErr == nil && wfp != "" {
+				var n int
+				n, writeErr = io.WriteString(w, wfp)
+				written += int64(n)
+				if writeErr != nil {
+					cancel()
+				}
+			}
+			if opts.Progress != nil {
+				fmt.Fprintf(opts.Progress, "progress:%d/%d\n", next, len(wfps))
+			}
 		}
 	}
 
-	if result == "" {
-		return "", fmt.Errorf("failed to generate any fingerprints")
+	if writeErr != nil {
+		return fmt.Errorf("error writing WFP: %v", writeErr)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	elapsed := time.Since(start).Seconds()
+	if elapsed > 0 {
+		DebugLog("Fingerprinted %d files (%.2f MB) in %.2fs: %.1f files/s, %.2f MB/s\n",
+			len(wfps), float64(wfpsSize)/1e6, elapsed, float64(len(wfps))/elapsed, float64(wfpsSize)/1e6/elapsed)
 	}
 
-	return result, nil
+	if written == 0 && opts.GitDiff == "" {
+		return fmt.Errorf("failed to generate any fingerprints")
+	}
+
+	return nil
 }
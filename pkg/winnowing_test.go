@@ -6,7 +6,10 @@
 package pkg
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -46,7 +49,7 @@ func main() {
 		t.Error("WFP should start with 'file='")
 	}
 
-	if !strings.Contains(wfp, tmpFile.Name()) {
+	if !strings.Contains(wfp, ","+filepath.Base(tmpFile.Name())+"\n") {
 		t.Error("WFP should contain file path")
 	}
 
@@ -146,3 +149,159 @@ func TestLoadFilters(t *testing.T) {
 		t.Error(".zip should be in onlyMD5")
 	}
 }
+
+func TestGenerateWFPFromDirectory_DeterministicOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		content := fmt.Sprintf("// file %d\n%s", i, strings.Repeat(fmt.Sprintf("int value_%d = compute(%d, input);\n", i, i), 20))
+		if err := os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("file%02d.c", i)), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+
+	sequential, err := GenerateWFPFromDirectoryWithProgress(tmpDir, nil)
+	if err != nil {
+		t.Fatalf("failed to generate WFP: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 8}); err != nil {
+		t.Fatalf("failed to generate WFP: %v", err)
+	}
+	parallel := buf.String()
+
+	if sequential != parallel {
+		t.Error("parallel WFP generation should produce the same output as sequential")
+	}
+	if strings.Count(parallel, "file=") != 20 {
+		t.Errorf("expected 20 files in WFP, got %d", strings.Count(parallel, "file="))
+	}
+}
+
+// failingWriter accepts a limited number of bytes and then fails
+type failingWriter struct {
+	limit int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > fw.limit {
+		return 0, fmt.Errorf("disk full")
+	}
+	fw.limit -= len(p)
+	return len(p), nil
+}
+
+func TestGenerateWFP_Stream(t *testing.T) {
+	tmpDir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		content := strings.Repeat(fmt.Sprintf("printf(\"line %d of the streaming test\\n\", counter_%d);\n", i, i), 20)
+		if err := os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("stream%d.c", i)), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+	}
+
+	expected, err := GenerateWFPFromDirectoryWithProgress(tmpDir, nil)
+	if err != nil {
+		t.Fatalf("failed to generate WFP: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := GenerateWFP(context.Background(), tmpDir, &buf, WFPOptions{Threads: 4}); err != nil {
+		t.Fatalf("failed to stream WFP: %v", err)
+	}
+	if buf.String() != expected {
+		t.Error("streamed WFP should match the in-memory WFP")
+	}
+
+	if err := GenerateWFP(context.Background(), tmpDir, &failingWriter{limit: 100}, WFPOptions{Threads: 4}); err == nil {
+		t.Error("expected error from failing writer, got nil")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := GenerateWFP(ctx, tmpDir, &buf, WFPOptions{Threads: 4}); err == nil {
+		t.Error("expected error from cancelled context, got nil")
+	}
+}
+
+func TestWFPOptions_WFPPath(t *testing.T) {
+	root := filepath.Join("..", "project", "src")
+	file := filepath.Join(root, "lib", "util.c")
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		t.Fatalf("failed to get absolute path: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts WFPOptions
+		root string
+		want string
+	}{
+		{"relative", WFPOptions{}, root, "lib/util.c"},
+		{"prefix", WFPOptions{PathPrefix: "acme/"}, root, "acme/lib/util.c"},
+		{"single file", WFPOptions{}, file, "util.c"},
+		{"single file with prefix", WFPOptions{PathPrefix: "/acme"}, file, "acme/util.c"},
+		{"absolute", WFPOptions{AbsolutePaths: true}, root, filepath.ToSlash(abs)},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.wfpPath(tt.root, file); got != tt.want {
+			t.Errorf("%s: wfpPath() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateWFP_RelativePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := strings.Repeat("int compute(int value) { return value * 2; }\n", 10)
+	if err := os.MkdirAll(filepath.Join(tmpDir, "src", "lib"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "src", "lib", "util.c"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	// The same tree reached through different paths produces the same WFP
+	var direct, indirect strings.Builder
+	if err := GenerateWFP(context.Background(), filepath.Join(tmpDir, "src"), &direct, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	winding := filepath.Join(tmpDir, "src", "lib", "..", "..", "src") + string(filepath.Separator)
+	if err := GenerateWFP(context.Background(), winding, &indirect, WFPOptions{}); err != nil {
+		t.Fatalf("GenerateWFP failed: %v", err)
+	}
+	if direct.String() != indirect.String() {
+		t.Errorf("WFP depends on the scan root spelling:\n%s\n%s", direct.String(), indirect.String())
+	}
+	if !strings.Contains(direct.String(), ",lib/util.c\n") {
+		t.Errorf("expected path relative to the scan root, got:\n%s", direct.String())
+	}
+}
+
+func TestGenerateWFPFromData(t *testing.T) {
+	snippet := "func add(first, second int) int {\n\treturn first + second\n}\n\n" +
+		"func subtract(first, second int) int {\n\treturn first - second\n}\n\n" +
+		"func multiply(first, second int) int {\n\treturn first * second\n}\n"
+
+	wfp, err := GenerateWFPFromData("snippet.go", []byte(snippet), WFPOptions{})
+	if err != nil {
+		t.Fatalf("GenerateWFPFromData failed: %v", err)
+	}
+	if !strings.HasPrefix(wfp, fmt.Sprintf("file=%s,%d,snippet.go\n", md5Hex([]byte(snippet)), len(snippet))) {
+		t.Errorf("unexpected WFP header:\n%s", wfp)
+	}
+	// Line numbers are relative to the snippet
+	lines := strings.Count(snippet, "\n")
+	for _, line := range strings.Split(wfp, "\n")[2:] {
+		var lineNum int
+		if _, err := fmt.Sscanf(line, "%d=", &lineNum); err == nil && (lineNum < 1 || lineNum > lines) {
+			t.Errorf("hash line %d outside the snippet (%d lines)", lineNum, lines)
+		}
+	}
+
+	for _, input := range []string{"", "  \n", "x := 1\n"} {
+		if _, err := GenerateWFPFromData("snippet.go", []byte(input), WFPOptions{}); err == nil {
+			t.Errorf("GenerateWFPFromData(%q) should fail", input)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+/*
+ * Rolling checksum used by the golden WFP tests.
+ */
+#include <stdint.h>
+#include <stddef.h>
+
+#define MOD_ADLER 65521
+
+uint32_t rolling_checksum(const unsigned char *data, size_t len)
+{
+	uint32_t a = 1, b = 0;
+	size_t index;
+
+	for (index = 0; index < len; ++index) {
+		a = (a + data[index]) % MOD_ADLER;
+		b = (b + a) % MOD_ADLER;
+	}
+
+	return (b << 16) | a;
+}
+
+int checksum_matches(const unsigned char *data, size_t len, uint32_t expected)
+{
+	if (data == NULL || len == 0) {
+		return 0;
+	}
+
+	return rolling_checksum(data, len) == expected;
+}
@@ -0,0 +1,147 @@
+#!/usr/bin/env python3
+# SPDX-FileCopyrightText: Copyright (C) 2025 Fundación Para La Transparencia del Software - STF
+# SPDX-FileCopyrightText: 2025 Mariano Scasso <info@st.foundation>
+#
+# SPDX-License-Identifier:GPL-2.0-only
+"""Writes the reference WFP of the golden sources, independently of the Go generator.
+
+The fingerprint follows the winnowing of scanoss-py (scanoss/winnowing.py): file= MD5 and
+size, fh2= MD5 with swapped line endings, hpsm= CRC-8/MAXIM per line, and CRC32C winnowing
+of 30-byte grams in 64-gram windows. Only the standard library is used.
+
+Usage: python3 wfp.py checksum.c wordcount_crlf.py
+"""
+
+import hashlib
+import os
+import sys
+
+GRAM = 30
+WINDOW = 64
+MAX_CRC32 = 0xFFFFFFFF
+
+
+def _crc32c_table():
+    table = []
+    for i in range(256):
+        crc = i
+        for _ in range(8):
+            crc = (crc >> 1) ^ 0x82F63B78 if crc & 1 else crc >> 1
+        table.append(crc)
+    return table
+
+
+CRC32C_TABLE = _crc32c_table()
+
+
+def crc32c(data):
+    crc = 0xFFFFFFFF
+    for b in data:
+        crc = CRC32C_TABLE[(crc ^ b) & 0xFF] ^ (crc >> 8)
+    return crc ^ 0xFFFFFFFF
+
+
+def crc8_maxim(data):
+    crc = 0
+    for b in data:
+        crc ^= b
+        for _ in range(8):
+            crc = (crc >> 1) ^ 0x8C if crc & 1 else crc >> 1
+    return crc
+
+
+def normalize(b):
+    """Keeps digits and lowercase letters, lowercases uppercase letters, drops the rest."""
+    if ord('0') <= b <= ord('9') or ord('a') <= b <= ord('z'):
+        return b
+    if ord('A') <= b <= ord('Z'):
+        return b + 32
+    return 0
+
+
+def fh2(contents):
+    if b'\r' not in contents and b'\n' not in contents:
+        return ''
+    if b'\r\n' in contents:
+        swapped = contents.replace(b'\r\n', b'\n').replace(b'\r', b'\n')
+    else:
+        swapped = contents.replace(b'\r', b'\n').replace(b'\n', b'\r\n')
+    return hashlib.md5(swapped).hexdigest()
+
+
+def hpsm(contents):
+    crcs = []
+    normalized = []
+    last_line = 0
+    for i, b in enumerate(contents):
+        if b == ord('\n'):
+            if normalized:
+                crcs.append(crc8_maxim(normalized))
+                normalized = []
+            elif last_line + 1 == i:
+                crcs.append(0xFF)
+            elif i - last_line > 1:
+                crcs.append(0x00)
+            last_line = i
+        else:
+            n = normalize(b)
+            if n:
+                normalized.append(n)
+    return ''.join('{:02x}'.format(c) for c in crcs)
+
+
+def wfp_for_contents(name, contents):
+    wfp = 'file={0},{1},{2}\n'.format(hashlib.md5(contents).hexdigest(), len(contents), name)
+    line_endings = fh2(contents)
+    if line_endings:
+        wfp += 'fh2={0}\n'.format(line_endings)
+    lines = hpsm(contents)
+    if lines:
+        wfp += 'hpsm={0}\n'.format(lines)
+
+    gram = b''
+    window = []
+    line = 1
+    last_hash = MAX_CRC32
+    last_line = 0
+    output = ''
+    for b in contents:
+        if b == ord('\n'):
+            line += 1
+            continue
+        n = normalize(b)
+        if not n:
+            continue
+        gram += bytes([n])
+        if len(gram) < GRAM:
+            continue
+        window.append(crc32c(gram))
+        if len(window) >= WINDOW:
+            min_hash = min(window)
+            if min_hash != last_hash:
+                crc_hex = '{:08x}'.format(crc32c(min_hash.to_bytes(4, 'little')))
+                if last_line != line:
+                    if output:
+                        wfp += output + '\n'
+                    output = '{0}={1}'.format(line, crc_hex)
+                else:
+                    output += ',' + crc_hex
+                last_line = line
+                last_hash = min_hash
+            window.pop(0)
+        gram = gram[1:]
+    if output:
+        wfp += output + '\n'
+    return wfp
+
+
+def main(paths):
+    for path in paths:
+        with open(path, 'rb') as f:
+            contents = f.read()
+        with open(path + '.wfp', 'w', newline='') as f:
+            f.write(wfp_for_contents(os.path.basename(path), contents))
+
+
+if __name__ == '__main__':
+    main(sys.argv[1:])
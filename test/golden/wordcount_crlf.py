@@ -0,0 +1,25 @@
+#!/usr/bin/env python3
+"""Word frequency counter used by the golden WFP tests."""
+
+import sys
+from collections import Counter
+
+
+def count_words(stream):
+    counter = Counter()
+    for line in stream:
+        for word in line.lower().split():
+            word = word.strip(".,;:!?\"'()[]")
+            if word:
+                counter[word] += 1
+    return counter
+
+
+def main():
+    counter = count_words(sys.stdin)
+    for word, total in counter.most_common(10):
+        print(f"{total:6d} {word}")
+
+
+if __name__ == "__main__":
+    main()